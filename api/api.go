@@ -0,0 +1,104 @@
+// Package api holds the JSON types of the REST API under /api/v1 and its
+// OpenAPI description. The server and the client package share the types,
+// and the contract test in the main package checks the description against
+// the server's routes and responses.
+package api
+
+import (
+	_ "embed"
+	"time"
+)
+
+// Spec is the OpenAPI 3 description of every route the server registers,
+// served at /api/v1/openapi.yaml.
+//
+//go:embed openapi.yaml
+var Spec []byte
+
+// Channel is a subscription.
+type Channel struct {
+	ChannelID    string   `json:"channel_id"`
+	Name         string   `json:"name"`
+	Alias        string   `json:"alias"`
+	Note         string   `json:"note"`
+	FeedURL      string   `json:"feed_url"`
+	AvatarURL    string   `json:"avatar_url"`
+	Groups       []string `json:"groups"`
+	ShortsMode   string   `json:"shorts_mode"`
+	HideLiveVODs bool     `json:"hide_live_vods"`
+	Priority     int      `json:"priority"`
+}
+
+// AddChannelRequest subscribes to a channel by handle, channel ID or channel
+// URL.
+type AddChannelRequest struct {
+	Channel string `json:"channel"`
+}
+
+// Video is a feed item. Durations and view counts are zero when unknown.
+type Video struct {
+	VideoID         string     `json:"video_id"`
+	Title           string     `json:"title"`
+	URL             string     `json:"url"`
+	ChannelID       string     `json:"channel_id"`
+	ChannelName     string     `json:"channel_name"`
+	PublishedAt     time.Time  `json:"published_at"`
+	DurationSeconds int64      `json:"duration_seconds"`
+	Views           int64      `json:"views"`
+	IsShort         bool       `json:"is_short"`
+	IsLive          bool       `json:"is_live"`
+	WasLive         bool       `json:"was_live"`
+	IsUpcoming      bool       `json:"is_upcoming"`
+	ScheduledStart  *time.Time `json:"scheduled_start,omitempty"`
+	Watched         bool       `json:"watched"`
+	IsNew           bool       `json:"is_new"`
+}
+
+// FeedPage is a page of feed items. NextCursor is empty on the last page.
+type FeedPage struct {
+	Items      []Video `json:"items"`
+	NextCursor string  `json:"next_cursor"`
+}
+
+// FeedSettings are the feed options the app opens with. Channels are
+// YouTube channel IDs; empty means all of them.
+type FeedSettings struct {
+	Channels   []string `json:"channels"`
+	ShowShorts bool     `json:"show_shorts"`
+	Sort       string   `json:"sort"`
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	OnlyNew    bool     `json:"only_new"`
+	View       int      `json:"view"`
+}
+
+// Settings are the user's settings.
+type Settings struct {
+	Theme    string       `json:"theme"`
+	Timezone string       `json:"timezone"`
+	Feed     FeedSettings `json:"feed"`
+}
+
+// SettingsPatch is a partial update of the settings. Fields left nil stay as
+// they are.
+type SettingsPatch struct {
+	Theme    *string            `json:"theme,omitempty"`
+	Timezone *string            `json:"timezone,omitempty"`
+	Feed     *FeedSettingsPatch `json:"feed,omitempty"`
+}
+
+// FeedSettingsPatch is a partial update of the feed settings.
+type FeedSettingsPatch struct {
+	Channels   *[]string `json:"channels,omitempty"`
+	ShowShorts *bool     `json:"show_shorts,omitempty"`
+	Sort       *string   `json:"sort,omitempty"`
+	From       *string   `json:"from,omitempty"`
+	To         *string   `json:"to,omitempty"`
+	OnlyNew    *bool     `json:"only_new,omitempty"`
+	View       *int      `json:"view,omitempty"`
+}
+
+// Error is the body of every error response.
+type Error struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"yt_rss2/api"
+	"yt_rss2/database"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+// These tests check api/openapi.yaml against the server: every route is
+// described and every described route exists, and the API's responses have
+// the statuses and shapes the description gives them.
+
+const (
+	contractChannelID = "UCabcdefghijklmnopqrstuv"
+	contractToken     = "ytr_contract-test-token"
+)
+
+func loadSpec(t *testing.T) map[string]any {
+	t.Helper()
+	var spec map[string]any
+	if err := yaml.Unmarshal(api.Spec, &spec); err != nil {
+		t.Fatalf("parsing the OpenAPI description: %v", err)
+	}
+	return spec
+}
+
+// muxVarRegex matches the variables in mux path templates, with the pattern
+// mux allows after the name.
+var muxVarRegex = regexp.MustCompile(`\{(\w+)(?::[^}]*)?\}`)
+
+func TestRoutesMatchSpec(t *testing.T) {
+	paths := loadSpec(t)["paths"].(map[string]any)
+
+	// described holds "METHOD path" for every route, and anyMethod the paths
+	// of routes that don't restrict the method.
+	described := make(map[string]bool)
+	anyMethod := make(map[string]bool)
+	err := newRouter().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		path := muxVarRegex.ReplaceAllString(template, "{$1}")
+
+		operations, ok := paths[path].(map[string]any)
+		if !ok {
+			t.Errorf("route %s isn't in the OpenAPI description", path)
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			anyMethod[path] = true
+			return nil
+		}
+		for _, method := range methods {
+			if _, ok := operations[strings.ToLower(method)]; !ok {
+				t.Errorf("route %s %s isn't in the OpenAPI description", method, path)
+			}
+			described[method+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, operations := range paths {
+		for method := range operations.(map[string]any) {
+			if method == "parameters" || anyMethod[path] {
+				continue
+			}
+			if !described[strings.ToUpper(method)+" "+path] {
+				t.Errorf("%s %s is described but has no route", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestAPIResponsesMatchSpec(t *testing.T) {
+	spec := loadSpec(t)
+	router := newContractServer(t)
+
+	channelPath := "/api/v1/channels/" + contractChannelID
+	tests := []struct {
+		method   string
+		path     string
+		specPath string
+		body     string
+		noToken  bool
+		status   int
+	}{
+		{"GET", "/api/v1/openapi.yaml", "/api/v1/openapi.yaml", "", true, 200},
+		{"GET", "/api/v1/channels", "/api/v1/channels", "", true, 401},
+		{"GET", "/api/v1/channels", "/api/v1/channels", "", false, 200},
+		{"POST", "/api/v1/channels", "/api/v1/channels", `{"channel": "` + contractChannelID + `"}`, false, 201},
+		{"POST", "/api/v1/channels", "/api/v1/channels", `{"channel": "` + contractChannelID + `"}`, false, 409},
+		{"POST", "/api/v1/channels", "/api/v1/channels", `{"channel": "UCzzzzzzzzzzzzzzzzzzzzzz"}`, false, 404},
+		{"POST", "/api/v1/channels", "/api/v1/channels", `{"channel": `, false, 400},
+		{"GET", "/api/v1/channels", "/api/v1/channels", "", false, 200},
+		{"GET", "/api/v1/feed", "/api/v1/feed", "", false, 200},
+		{"GET", "/api/v1/feed?limit=1", "/api/v1/feed", "", false, 200},
+		{"GET", "/api/v1/feed?limit=0", "/api/v1/feed", "", false, 400},
+		{"GET", "/api/v1/feed?from=yesterday", "/api/v1/feed", "", false, 400},
+		{"GET", "/api/v1/feed?to=2026-13-01", "/api/v1/feed", "", false, 400},
+		{"GET", "/api/v1/feed?from=2020-01-01&to=2099-12-31", "/api/v1/feed", "", false, 200},
+		{"GET", "/api/v1/feed?cursor=nonsense", "/api/v1/feed", "", false, 400},
+		{"GET", "/api/v1/feed?view=99", "/api/v1/feed", "", false, 404},
+		{"PUT", "/api/v1/watched/vid00000001", "/api/v1/watched/{id}", "", false, 204},
+		{"PUT", "/api/v1/watched/nope", "/api/v1/watched/{id}", "", false, 400},
+		{"GET", "/api/v1/watched", "/api/v1/watched", "", false, 200},
+		{"DELETE", "/api/v1/watched/vid00000001", "/api/v1/watched/{id}", "", false, 204},
+		{"GET", "/api/v1/settings", "/api/v1/settings", "", false, 200},
+		{"PATCH", "/api/v1/settings", "/api/v1/settings", `{"theme": "nord", "feed": {"sort": "oldest"}}`, false, 200},
+		{"PATCH", "/api/v1/settings", "/api/v1/settings", `{"feed": {"from": "soon"}}`, false, 400},
+		{"DELETE", channelPath, "/api/v1/channels/{id}", "", false, 204},
+		{"DELETE", channelPath, "/api/v1/channels/{id}", "", false, 404},
+	}
+
+	for _, test := range tests {
+		name := test.method + " " + test.path
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if !test.noToken {
+			req.Header.Set("Authorization", "Bearer "+contractToken)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("%s: got status %d, want %d: %s", name, rec.Code, test.status, rec.Body)
+			continue
+		}
+		for _, problem := range checkResponse(spec, test.specPath, test.method, rec) {
+			t.Errorf("%s: %s", name, problem)
+		}
+	}
+}
+
+// newContractServer points the app at a new database with one user, who has
+// the API token contractToken, and at a stand-in for YouTube.
+func newContractServer(t *testing.T) *mux.Router {
+	t.Helper()
+
+	store, err := database.OpenSQLite(filepath.Join(t.TempDir(), "yt_rss.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	previousDB := database.DB
+	database.DB = store
+	t.Cleanup(func() { database.DB = previousDB })
+
+	previousTransport := http.DefaultTransport
+	http.DefaultTransport = fakeYouTube{}
+	t.Cleanup(func() { http.DefaultTransport = previousTransport })
+
+	if err := store.CreateUser("alice", "unused"); err != nil {
+		t.Fatal(err)
+	}
+	user, _, err := store.UserByName("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(contractToken))
+	if err := store.CreateAPIToken(user.ID, "contract", hex.EncodeToString(sum[:]), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return newRouter()
+}
+
+// fakeYouTube answers for contractChannelID's page and feed, and with 404
+// for anything else.
+type fakeYouTube struct{}
+
+func (fakeYouTube) RoundTrip(req *http.Request) (*http.Response, error) {
+	feedURL := "https://www.youtube.com/feeds/videos.xml?channel_id=" + contractChannelID
+	var body, contentType string
+	switch req.URL.String() {
+	case "https://www.youtube.com/channel/" + contractChannelID:
+		contentType = "text/html"
+		body = `<html><head>
+<meta property="og:title" content="Alpha">
+<meta property="og:image" content="https://yt3.ggpht.com/alpha.jpg">
+<link rel="alternate" type="application/rss+xml" title="RSS" href="` + feedURL + `">
+</head></html>`
+	case feedURL:
+		contentType = "application/atom+xml"
+		var entries strings.Builder
+		now := time.Now().UTC().Truncate(time.Second)
+		for i := range 3 {
+			videoID := fmt.Sprintf("vid%08d", i)
+			fmt.Fprintf(&entries, `<entry>
+<id>yt:video:%[1]s</id><yt:videoId>%[1]s</yt:videoId><yt:channelId>%[2]s</yt:channelId>
+<title>Video %[3]d</title><link rel="alternate" href="https://www.youtube.com/watch?v=%[1]s"/>
+<published>%[4]s</published><updated>%[4]s</updated>
+</entry>`, videoID, contractChannelID, i, now.Add(-time.Duration(i)*time.Hour).Format(time.RFC3339))
+		}
+		body = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns:media="http://search.yahoo.com/mrss/" xmlns="http://www.w3.org/2005/Atom">
+<title>Alpha</title>` + entries.String() + `</feed>`
+	default:
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Status:     "404 Not Found",
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{"Content-Type": {contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// checkResponse compares a response with what the description says the
+// operation answers with that status.
+func checkResponse(spec map[string]any, path, method string, rec *httptest.ResponseRecorder) []string {
+	operation, ok := lookup(spec, "paths", path, strings.ToLower(method)).(map[string]any)
+	if !ok {
+		return []string{"operation isn't described"}
+	}
+	response, ok := lookup(operation, "responses", strconv.Itoa(rec.Code)).(map[string]any)
+	if !ok {
+		return []string{fmt.Sprintf("status %d isn't described", rec.Code)}
+	}
+	response = resolve(spec, response)
+
+	content, ok := response["content"].(map[string]any)
+	if !ok {
+		if rec.Body.Len() > 0 {
+			return []string{"described without a body, but has one"}
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	media, ok := content[mediaType].(map[string]any)
+	if !ok {
+		return []string{fmt.Sprintf("content type %q isn't described", mediaType)}
+	}
+	if mediaType != "application/json" {
+		return nil
+	}
+
+	decoder := json.NewDecoder(rec.Body)
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []string{"invalid JSON: " + err.Error()}
+	}
+	return checkSchema(spec, media["schema"].(map[string]any), value, "body")
+}
+
+// checkSchema checks a decoded JSON value against the parts of JSON Schema
+// the description uses.
+func checkSchema(spec, schema map[string]any, value any, at string) []string {
+	schema = resolve(spec, schema)
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return []string{fmt.Sprintf("%s: %v isn't one of %v", at, value, enum)}
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []string{at + ": not an object"}
+		}
+		for _, name := range asSlice(schema["required"]) {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing %s", at, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, field := range object {
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: %s isn't described", at, name))
+				continue
+			}
+			problems = append(problems, checkSchema(spec, property, field, at+"."+name)...)
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return []string{at + ": not an array"}
+		}
+		for i, element := range array {
+			problems = append(problems, checkSchema(spec, schema["items"].(map[string]any), element, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{at + ": not a string"}
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(str) {
+			problems = append(problems, fmt.Sprintf("%s: %q doesn't match %s", at, str, pattern))
+		}
+		switch schema["format"] {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %q isn't a date-time", at, str))
+			}
+		case "uri":
+			if parsed, err := url.Parse(str); err != nil || parsed.Scheme == "" {
+				problems = append(problems, fmt.Sprintf("%s: %q isn't a URI", at, str))
+			}
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return []string{at + ": not a number"}
+		}
+		if _, err := number.Int64(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s isn't an integer", at, number))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{at + ": not a boolean"}
+		}
+	}
+	return problems
+}
+
+// resolve follows a $ref to another part of the description.
+func resolve(spec, node map[string]any) map[string]any {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+	keys := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+	return resolve(spec, lookup(spec, keys...).(map[string]any))
+}
+
+func lookup(node any, keys ...string) any {
+	for _, key := range keys {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = object[key]
+	}
+	return node
+}
+
+func asSlice(value any) []any {
+	slice, _ := value.([]any)
+	return slice
+}
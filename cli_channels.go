@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"yt_rss2/database"
+	"yt_rss2/handlers"
+)
+
+// channelsCommand imports and exports a user's subscriptions.
+func channelsCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("channels needs a subcommand: import or export")
+	}
+	subcommand, args := args[0], args[1:]
+
+	flags := flag.NewFlagSet("channels "+subcommand, flag.ExitOnError)
+	username := flags.String("user", "", "the user whose subscriptions to use")
+	format := flags.String("format", "json", "export format: "+strings.Join(handlers.ExportFormats(), ", "))
+	includeSettings := flags.Bool("settings", false, "include groups and per-channel settings in CSV; JSON always has them")
+	files := parseFlags(flags, args)
+
+	if *username == "" {
+		return fmt.Errorf("channels %s needs -user", subcommand)
+	}
+	database.InitDB()
+	userID, err := userIDByName(*username)
+	if err != nil {
+		return err
+	}
+
+	switch subcommand {
+	case "import":
+		if len(files) != 1 {
+			return errors.New("channels import needs a file, or - for stdin")
+		}
+		var data []byte
+		if files[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(files[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		added, total, err := handlers.ImportSubscriptions(userID, data)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d of %d channels for %s; the rest were invalid or already there.\n", added, total, *username)
+	case "export":
+		if !slices.Contains(handlers.ExportFormats(), *format) {
+			return fmt.Errorf("unknown export format %q", *format)
+		}
+		data, err := handlers.ExportSubscriptions(userID, *format, *includeSettings)
+		if err != nil {
+			return err
+		}
+		switch len(files) {
+		case 0:
+			_, err = os.Stdout.Write(data)
+			return err
+		case 1:
+			return os.WriteFile(files[0], data, 0o644)
+		}
+		return errors.New("channels export takes at most one file")
+	default:
+		return fmt.Errorf("unknown channels subcommand %q", subcommand)
+	}
+	return nil
+}
+
+// parseFlags parses flags wherever they are among the arguments, so
+// "channels import FILE -user NAME" works too, and returns the other
+// arguments. flag.Parse stops at the first one that isn't a flag. Arguments
+// after "--" are never flags.
+func parseFlags(flags *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		flags.Parse(args)
+		rest := flags.Args()
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			return append(positional, rest...)
+		}
+		if len(rest) == 0 {
+			return positional
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// fetchCommand fetches every channel's feed, so the stored history and feed
+// health are up to date without anyone opening the app. It's meant for cron.
+func fetchCommand(args []string) error {
+	flags := flag.NewFlagSet("fetch", flag.ExitOnError)
+	flags.Parse(args)
+
+	database.InitDB()
+	channels, videos, err := handlers.RefreshFeeds()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Fetched %d videos from %d channels.\n", videos, channels)
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"yt_rss2/database"
+)
+
+// dbCommand maintains the database.
+func dbCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("db needs a subcommand: migrate or backup")
+	}
+
+	switch args[0] {
+	case "migrate":
+		database.Open()
+		applied, err := database.DB.Migrate()
+		for _, migration := range applied {
+			fmt.Printf("Applied migration %d: %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		version, err := database.DB.SchemaVersion()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("The database schema is at version %d.\n", version)
+	case "backup":
+		if database.UsesPostgres() {
+			return errors.New("db backup only works with SQLite; back up PostgreSQL with pg_dump")
+		}
+		if len(args) != 2 {
+			return errors.New("db backup needs the file to write")
+		}
+		if _, err := os.Stat(args[1]); err == nil {
+			return fmt.Errorf("%s already exists", args[1])
+		}
+		database.InitDB()
+		if err := database.DB.Backup(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Backed up the database to %s.\n", args[1])
+	default:
+		return fmt.Errorf("unknown db subcommand %q", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"yt_rss2/database"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+// userCommand manages user accounts.
+func userCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("user needs a subcommand: add, list, passwd or delete")
+	}
+	database.InitDB()
+
+	subcommand, args := args[0], args[1:]
+	if subcommand == "list" {
+		return listUsers()
+	}
+
+	flags := flag.NewFlagSet("user "+subcommand, flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 || strings.TrimSpace(flags.Arg(0)) == "" {
+		return fmt.Errorf("user %s needs a username", subcommand)
+	}
+	username := flags.Arg(0)
+
+	switch subcommand {
+	case "add":
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		if err := database.DB.CreateUser(username, string(hash)); err != nil {
+			return fmt.Errorf("creating user %s: %w", username, err)
+		}
+		fmt.Printf("Created user %s.\n", username)
+	case "passwd":
+		userID, err := userIDByName(username)
+		if err != nil {
+			return err
+		}
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		if err := database.DB.SetPassword(userID, string(hash)); err != nil {
+			return err
+		}
+		fmt.Printf("Changed the password of %s.\n", username)
+	case "delete":
+		userID, err := userIDByName(username)
+		if err != nil {
+			return err
+		}
+		if err := database.DB.DeleteUser(userID); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted user %s.\n", username)
+	default:
+		return fmt.Errorf("unknown user subcommand %q", subcommand)
+	}
+	return nil
+}
+
+func listUsers() error {
+	users, err := database.DB.Users()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSERNAME\tCHANNELS")
+	for _, user := range users {
+		fmt.Fprintf(w, "%d\t%s\t%d\n", user.ID, user.Username, user.Channels)
+	}
+	return w.Flush()
+}
+
+// userIDByName looks a user up by username.
+func userIDByName(username string) (int, error) {
+	user, _, err := database.DB.UserByName(username)
+	if err == database.ErrNotFound {
+		return 0, fmt.Errorf("no user named %s", username)
+	}
+	return user.ID, err
+}
+
+// readPassword reads a password from stdin. On a terminal it prompts for it
+// without echoing; otherwise it takes the first line.
+func readPassword() (string, error) {
+	var password string
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Password: ")
+		input, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("reading the password: %w", err)
+		}
+		password = string(input)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", errors.New("no password given on stdin")
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return "", errors.New("the password can't be empty")
+	}
+	return password, nil
+}
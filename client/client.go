@@ -0,0 +1,90 @@
+// Package client is a typed Go client for the JSON API described in
+// api/openapi.yaml. Its operations, in operations.go, are generated from the
+// description by client/gen.
+//
+//	c := client.New("https://yt.example.com", "ytr_…")
+//	page, err := c.GetFeed(ctx, client.GetFeedParams{Sort: "newest", Limit: 20})
+package client
+
+//go:generate go run ./gen ../api/openapi.yaml operations.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"yt_rss2/api"
+)
+
+// Client talks to one server as one user.
+type Client struct {
+	// BaseURL is where the app is served, without a trailing slash.
+	BaseURL string
+	// Token is a personal access token.
+	Token string
+	// HTTPClient sends the requests. It defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// New returns a client for the app at baseURL, authenticated with token.
+func New(baseURL, token string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Token: token}
+}
+
+// Error is an error response from the API.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// do sends a request to the API, encoding body as JSON if it isn't nil and
+// decoding the response into result if it isn't nil.
+func (c *Client) do(ctx context.Context, method, path string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+"/api/v1"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var apiErr api.Error
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+			apiErr.Error = http.StatusText(resp.StatusCode)
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: apiErr.Error}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
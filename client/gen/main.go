@@ -0,0 +1,426 @@
+// Command gen writes the client's API operations from the OpenAPI
+// description. go generate runs it in the client package:
+//
+//	go generate ./client
+//
+// Every operation under /api/v1 with an operationId becomes a method on
+// Client. Object schemas map to the types of the same name in the api
+// package, which the server uses too.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// apiPrefix is the path the API is served under. Client.do adds it back.
+const apiPrefix = "/api/v1"
+
+// methods are the HTTP methods operations can have, in the order they're
+// written for each path.
+var methods = []string{"get", "put", "post", "patch", "delete"}
+
+func main() {
+	if len(os.Args) != 3 {
+		log.Fatal("usage: gen SPEC OUTPUT")
+	}
+	spec, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := generate(spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(os.Args[2], src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// operation is an API operation as the client calls it.
+type operation struct {
+	Name        string
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	PathParams  []param
+	QueryParams []param
+	// Body and Result are Go types, empty when there's no request or
+	// response body.
+	Body   string
+	Result string
+}
+
+// param is a path or query parameter.
+type param struct {
+	Name        string
+	GoName      string
+	GoType      string
+	Description string
+}
+
+// generate returns the Go source of the client's operations.
+func generate(specData []byte) ([]byte, error) {
+	var spec map[string]any
+	if err := yaml.Unmarshal(specData, &spec); err != nil {
+		return nil, err
+	}
+	operations, err := readOperations(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "// Code generated by client/gen from api/openapi.yaml. DO NOT EDIT.")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "package client")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "import (")
+	for _, pkg := range imports(operations) {
+		fmt.Fprintf(&b, "\t%q\n", pkg)
+	}
+	fmt.Fprintln(&b, ")")
+	for _, op := range operations {
+		writeOperation(&b, op)
+	}
+	return format.Source(b.Bytes())
+}
+
+// readOperations finds the API's operations, sorted by path and method.
+func readOperations(spec map[string]any) ([]operation, error) {
+	paths, _ := spec["paths"].(map[string]any)
+	var pathNames []string
+	for pathName := range paths {
+		if strings.HasPrefix(pathName, apiPrefix+"/") {
+			pathNames = append(pathNames, pathName)
+		}
+	}
+	sort.Strings(pathNames)
+
+	var operations []operation
+	for _, pathName := range pathNames {
+		item, _ := paths[pathName].(map[string]any)
+		for _, method := range methods {
+			node, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			id, _ := node["operationId"].(string)
+			if id == "" {
+				continue
+			}
+			op, err := readOperation(spec, item, node, id, method, strings.TrimPrefix(pathName, apiPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), pathName, err)
+			}
+			operations = append(operations, op)
+		}
+	}
+	return operations, nil
+}
+
+func readOperation(spec, item, node map[string]any, id, method, pathName string) (operation, error) {
+	op := operation{
+		Name:        exported(id),
+		Method:      method,
+		Path:        pathName,
+		Summary:     stringAt(node, "summary"),
+		Description: stringAt(node, "description"),
+	}
+
+	// Parameters can be shared by every operation on the path.
+	parameters := append(asSlice(item["parameters"]), asSlice(node["parameters"])...)
+	for _, raw := range parameters {
+		p := resolve(spec, raw.(map[string]any))
+		schema, _ := p["schema"].(map[string]any)
+		goType, err := goType(spec, schema)
+		if err != nil {
+			return op, fmt.Errorf("parameter %s: %w", stringAt(p, "name"), err)
+		}
+		parameter := param{
+			Name:        stringAt(p, "name"),
+			GoName:      exported(stringAt(p, "name")),
+			GoType:      goType,
+			Description: stringAt(p, "description"),
+		}
+		switch stringAt(p, "in") {
+		case "path":
+			if goType != "string" && goType != "int" {
+				return op, fmt.Errorf("path parameter %s is a %s", parameter.Name, goType)
+			}
+			op.PathParams = append(op.PathParams, parameter)
+		case "query":
+			if !slices.Contains([]string{"string", "int", "int64", "bool", "[]string"}, goType) {
+				return op, fmt.Errorf("query parameter %s is a %s", parameter.Name, goType)
+			}
+			op.QueryParams = append(op.QueryParams, parameter)
+		default:
+			return op, fmt.Errorf("parameter %s is in %s", parameter.Name, stringAt(p, "in"))
+		}
+	}
+
+	if body, ok := node["requestBody"].(map[string]any); ok {
+		schema, ok := lookup(resolve(spec, body), "content", "application/json", "schema").(map[string]any)
+		if !ok {
+			return op, fmt.Errorf("request body isn't JSON")
+		}
+		var err error
+		if op.Body, err = goType(spec, schema); err != nil {
+			return op, fmt.Errorf("request body: %w", err)
+		}
+	}
+
+	// The result is the JSON body of the first success response.
+	responses, _ := node["responses"].(map[string]any)
+	var statuses []string
+	for status := range responses {
+		if strings.HasPrefix(status, "2") {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Strings(statuses)
+	if len(statuses) == 0 {
+		return op, fmt.Errorf("no success response")
+	}
+	response := resolve(spec, responses[statuses[0]].(map[string]any))
+	if schema, ok := lookup(response, "content", "application/json", "schema").(map[string]any); ok {
+		var err error
+		if op.Result, err = goType(spec, schema); err != nil {
+			return op, fmt.Errorf("response: %w", err)
+		}
+	}
+	return op, nil
+}
+
+// goType returns the Go type of values matching schema.
+func goType(spec, schema map[string]any) (string, error) {
+	if ref, ok := schema["$ref"].(string); ok {
+		target := resolve(spec, schema)
+		if target["type"] == "object" {
+			return "api." + path.Base(ref), nil
+		}
+		return goType(spec, target)
+	}
+	switch schema["type"] {
+	case "string":
+		return "string", nil
+	case "integer":
+		if schema["format"] == "int64" {
+			return "int64", nil
+		}
+		return "int", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		element, err := goType(spec, items)
+		return "[]" + element, err
+	}
+	return "", fmt.Errorf("no Go type for schema %v", schema)
+}
+
+// imports lists the packages the operations use.
+func imports(operations []operation) []string {
+	used := map[string]bool{"context": true, "net/http": true}
+	for _, op := range operations {
+		for _, p := range op.PathParams {
+			used["net/url"] = true
+			if p.GoType == "int" {
+				used["strconv"] = true
+			}
+		}
+		for _, p := range op.QueryParams {
+			used["net/url"] = true
+			if p.GoType == "int" || p.GoType == "int64" {
+				used["strconv"] = true
+			}
+		}
+		if strings.Contains(op.Body+op.Result, "api.") {
+			used["yt_rss2/api"] = true
+		}
+	}
+	var packages []string
+	for pkg := range used {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+func writeOperation(b *bytes.Buffer, op operation) {
+	paramsType := op.Name + "Params"
+	if len(op.QueryParams) > 0 {
+		fmt.Fprintln(b)
+		writeComment(b, "", fmt.Sprintf("%s are the query parameters of %s. Zero fields are left out.", paramsType, op.Name))
+		fmt.Fprintf(b, "type %s struct {\n", paramsType)
+		for _, p := range op.QueryParams {
+			writeComment(b, "\t", p.Description)
+			fmt.Fprintf(b, "\t%s %s\n", p.GoName, p.GoType)
+		}
+		fmt.Fprintln(b, "}")
+
+		fmt.Fprintf(b, "\nfunc (p %s) values() url.Values {\n", paramsType)
+		fmt.Fprintln(b, "\tvalues := url.Values{}")
+		for _, p := range op.QueryParams {
+			field := "p." + p.GoName
+			switch p.GoType {
+			case "string":
+				fmt.Fprintf(b, "\tif %s != \"\" {\n\t\tvalues.Set(%q, %s)\n\t}\n", field, p.Name, field)
+			case "int":
+				fmt.Fprintf(b, "\tif %s != 0 {\n\t\tvalues.Set(%q, strconv.Itoa(%s))\n\t}\n", field, p.Name, field)
+			case "int64":
+				fmt.Fprintf(b, "\tif %s != 0 {\n\t\tvalues.Set(%q, strconv.FormatInt(%s, 10))\n\t}\n", field, p.Name, field)
+			case "bool":
+				fmt.Fprintf(b, "\tif %s {\n\t\tvalues.Set(%q, \"true\")\n\t}\n", field, p.Name)
+			case "[]string":
+				fmt.Fprintf(b, "\tfor _, value := range %s {\n\t\tvalues.Add(%q, value)\n\t}\n", field, p.Name)
+			}
+		}
+		fmt.Fprintln(b, "\treturn values")
+		fmt.Fprintln(b, "}")
+	}
+
+	// The signature: path parameters, then query parameters, then the body.
+	args := []string{"ctx context.Context"}
+	for _, p := range op.PathParams {
+		args = append(args, unexported(p.GoName)+" "+p.GoType)
+	}
+	if len(op.QueryParams) > 0 {
+		args = append(args, "params "+paramsType)
+	}
+	if op.Body != "" {
+		args = append(args, "body "+op.Body)
+	}
+	results := "error"
+	if op.Result != "" {
+		results = "(" + op.Result + ", error)"
+	}
+
+	fmt.Fprintln(b)
+	summary := strings.ToLower(op.Summary[:1]) + op.Summary[1:]
+	writeComment(b, "", fmt.Sprintf("%s calls %s %s to %s. %s", op.Name, strings.ToUpper(op.Method), apiPrefix+op.Path, summary, op.Description))
+	fmt.Fprintf(b, "func (c *Client) %s(%s) %s {\n", op.Name, strings.Join(args, ", "), results)
+
+	fmt.Fprintf(b, "\tpath := %s\n", pathExpression(op))
+	if len(op.QueryParams) > 0 {
+		fmt.Fprintln(b, "\tif values := params.values(); len(values) > 0 {")
+		fmt.Fprintln(b, "\t\tpath += \"?\" + values.Encode()")
+		fmt.Fprintln(b, "\t}")
+	}
+	body := "nil"
+	if op.Body != "" {
+		body = "body"
+	}
+	method := "http.Method" + exported(op.Method)
+	if op.Result == "" {
+		fmt.Fprintf(b, "\treturn c.do(ctx, %s, path, %s, nil)\n", method, body)
+	} else {
+		fmt.Fprintf(b, "\tvar result %s\n", op.Result)
+		fmt.Fprintf(b, "\terr := c.do(ctx, %s, path, %s, &result)\n", method, body)
+		fmt.Fprintln(b, "\treturn result, err")
+	}
+	fmt.Fprintln(b, "}")
+}
+
+// pathExpression returns a Go expression for the operation's path, with its
+// path parameters filled in.
+func pathExpression(op operation) string {
+	expression := fmt.Sprintf("%q", op.Path)
+	for _, p := range op.PathParams {
+		value := unexported(p.GoName)
+		if p.GoType == "int" {
+			value = "strconv.Itoa(" + value + ")"
+		}
+		expression = strings.ReplaceAll(expression, "{"+p.Name+"}", `" + url.PathEscape(`+value+`) + "`)
+	}
+	return strings.TrimSuffix(expression, ` + ""`)
+}
+
+// writeComment writes text as a Go comment wrapped at 80 columns, counting
+// a tab as four.
+func writeComment(b *bytes.Buffer, indent, text string) {
+	line := indent + "//"
+	for _, word := range strings.Fields(text) {
+		width := len(line) + 3*strings.Count(line, "\t")
+		if width+1+len(word) > 80 && line != indent+"//" {
+			fmt.Fprintln(b, line)
+			line = indent + "//"
+		}
+		line += " " + word
+	}
+	if line != indent+"//" {
+		fmt.Fprintln(b, line)
+	}
+}
+
+// exported turns an operation or parameter name into an exported Go name:
+// listChannels becomes ListChannels and show-shorts becomes ShowShorts.
+func exported(name string) string {
+	if name == "id" {
+		return "ID"
+	}
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '-' || r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unexported turns an exported Go name into a variable name.
+func unexported(name string) string {
+	if name == "ID" {
+		return "id"
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// resolve follows a $ref to another part of the description.
+func resolve(spec, node map[string]any) map[string]any {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+	target, _ := lookup(spec, strings.Split(strings.TrimPrefix(ref, "#/"), "/")...).(map[string]any)
+	return resolve(spec, target)
+}
+
+func lookup(node any, keys ...string) any {
+	for _, key := range keys {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = object[key]
+	}
+	return node
+}
+
+func stringAt(node map[string]any, key string) string {
+	value, _ := node[key].(string)
+	return value
+}
+
+func asSlice(value any) []any {
+	slice, _ := value.([]any)
+	return slice
+}
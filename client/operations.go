@@ -0,0 +1,138 @@
+// Code generated by client/gen from api/openapi.yaml. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"yt_rss2/api"
+)
+
+// ListChannels calls GET /api/v1/channels to list channels.
+func (c *Client) ListChannels(ctx context.Context) ([]api.Channel, error) {
+	path := "/channels"
+	var result []api.Channel
+	err := c.do(ctx, http.MethodGet, path, nil, &result)
+	return result, err
+}
+
+// AddChannel calls POST /api/v1/channels to add a channel.
+func (c *Client) AddChannel(ctx context.Context, body api.AddChannelRequest) (api.Channel, error) {
+	path := "/channels"
+	var result api.Channel
+	err := c.do(ctx, http.MethodPost, path, body, &result)
+	return result, err
+}
+
+// DeleteChannel calls DELETE /api/v1/channels/{id} to remove a channel.
+func (c *Client) DeleteChannel(ctx context.Context, id string) error {
+	path := "/channels/" + url.PathEscape(id)
+	return c.do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+// GetFeedParams are the query parameters of GetFeed. Zero fields are left out.
+type GetFeedParams struct {
+	// Only these channels.
+	Channel []string
+	// Only the channels in this group.
+	Group string
+	// Only the videos matching this saved view, by ID.
+	View       int
+	ShowShorts bool
+	Sort       string
+	From       string
+	To         string
+	// Only videos since the user's last visit.
+	OnlyNew bool
+	Limit   int
+	// The next_cursor of the previous page. It holds the publish time and ID of
+	// the last video on that page, so paging carries on where it left off even
+	// if that video has left the feed since.
+	Cursor string
+}
+
+func (p GetFeedParams) values() url.Values {
+	values := url.Values{}
+	for _, value := range p.Channel {
+		values.Add("channel", value)
+	}
+	if p.Group != "" {
+		values.Set("group", p.Group)
+	}
+	if p.View != 0 {
+		values.Set("view", strconv.Itoa(p.View))
+	}
+	if p.ShowShorts {
+		values.Set("show-shorts", "true")
+	}
+	if p.Sort != "" {
+		values.Set("sort", p.Sort)
+	}
+	if p.From != "" {
+		values.Set("from", p.From)
+	}
+	if p.To != "" {
+		values.Set("to", p.To)
+	}
+	if p.OnlyNew {
+		values.Set("only-new", "true")
+	}
+	if p.Limit != 0 {
+		values.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Cursor != "" {
+		values.Set("cursor", p.Cursor)
+	}
+	return values
+}
+
+// GetFeed calls GET /api/v1/feed to get a page of the feed. Without filters,
+// the feed has every channel, newest first.
+func (c *Client) GetFeed(ctx context.Context, params GetFeedParams) (api.FeedPage, error) {
+	path := "/feed"
+	if values := params.values(); len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+	var result api.FeedPage
+	err := c.do(ctx, http.MethodGet, path, nil, &result)
+	return result, err
+}
+
+// GetSettings calls GET /api/v1/settings to get settings.
+func (c *Client) GetSettings(ctx context.Context) (api.Settings, error) {
+	path := "/settings"
+	var result api.Settings
+	err := c.do(ctx, http.MethodGet, path, nil, &result)
+	return result, err
+}
+
+// UpdateSettings calls PATCH /api/v1/settings to change settings.
+func (c *Client) UpdateSettings(ctx context.Context, body api.SettingsPatch) (api.Settings, error) {
+	path := "/settings"
+	var result api.Settings
+	err := c.do(ctx, http.MethodPatch, path, body, &result)
+	return result, err
+}
+
+// ListWatched calls GET /api/v1/watched to list watched videos.
+func (c *Client) ListWatched(ctx context.Context) ([]string, error) {
+	path := "/watched"
+	var result []string
+	err := c.do(ctx, http.MethodGet, path, nil, &result)
+	return result, err
+}
+
+// MarkWatched calls PUT /api/v1/watched/{id} to mark a video as watched.
+func (c *Client) MarkWatched(ctx context.Context, id string) error {
+	path := "/watched/" + url.PathEscape(id)
+	return c.do(ctx, http.MethodPut, path, nil, nil)
+}
+
+// MarkUnwatched calls DELETE /api/v1/watched/{id} to mark a video as not
+// watched.
+func (c *Client) MarkUnwatched(ctx context.Context, id string) error {
+	path := "/watched/" + url.PathEscape(id)
+	return c.do(ctx, http.MethodDelete, path, nil, nil)
+}
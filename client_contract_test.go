@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"yt_rss2/api"
+	"yt_rss2/client"
+)
+
+// TestClientMatchesSpec drives every operation of the generated client
+// against the server. Each request has to be one the description has, and
+// each response has to have the shape it gives.
+func TestClientMatchesSpec(t *testing.T) {
+	spec := loadSpec(t)
+	checker := &specChecker{t: t, spec: spec, next: newContractServer(t), called: make(map[string]bool)}
+	server := httptest.NewServer(checker)
+	defer server.Close()
+
+	// newContractServer sends the default transport to a stand-in for
+	// YouTube, so the client needs its own.
+	c := client.New(server.URL, contractToken)
+	c.HTTPClient = &http.Client{Transport: &http.Transport{}}
+	ctx := context.Background()
+
+	channels, err := c.ListChannels(ctx)
+	check(t, "ListChannels", err)
+	if len(channels) != 0 {
+		t.Errorf("ListChannels before adding any = %+v", channels)
+	}
+
+	added, err := c.AddChannel(ctx, api.AddChannelRequest{Channel: contractChannelID})
+	check(t, "AddChannel", err)
+	if added.ChannelID != contractChannelID || added.Name != "Alpha" {
+		t.Errorf("AddChannel = %+v", added)
+	}
+	_, err = c.AddChannel(ctx, api.AddChannelRequest{Channel: contractChannelID})
+	checkStatus(t, "AddChannel of a channel already added", err, http.StatusConflict)
+
+	page, err := c.GetFeed(ctx, client.GetFeedParams{Channel: []string{contractChannelID}, Sort: "newest", Limit: 2})
+	check(t, "GetFeed", err)
+	if len(page.Items) != 2 || page.NextCursor == "" {
+		t.Fatalf("GetFeed = %d videos, next cursor %q, want 2 and a cursor", len(page.Items), page.NextCursor)
+	}
+	rest, err := c.GetFeed(ctx, client.GetFeedParams{Limit: 2, Cursor: page.NextCursor})
+	check(t, "GetFeed with a cursor", err)
+	if len(rest.Items) != 1 || rest.NextCursor != "" {
+		t.Errorf("GetFeed with a cursor = %d videos, next cursor %q, want 1 and none", len(rest.Items), rest.NextCursor)
+	}
+	_, err = c.GetFeed(ctx, client.GetFeedParams{From: "yesterday"})
+	checkStatus(t, "GetFeed with a bad date", err, http.StatusBadRequest)
+
+	videoID := page.Items[0].VideoID
+	check(t, "MarkWatched", c.MarkWatched(ctx, videoID))
+	watched, err := c.ListWatched(ctx)
+	check(t, "ListWatched", err)
+	if !slices.Equal(watched, []string{videoID}) {
+		t.Errorf("ListWatched = %v, want [%s]", watched, videoID)
+	}
+	check(t, "MarkUnwatched", c.MarkUnwatched(ctx, videoID))
+	if watched, _ := c.ListWatched(ctx); len(watched) != 0 {
+		t.Errorf("ListWatched after MarkUnwatched = %v", watched)
+	}
+
+	settings, err := c.GetSettings(ctx)
+	check(t, "GetSettings", err)
+	theme, sortOrder := "nord", "oldest"
+	if settings.Theme == theme {
+		t.Fatalf("the test user already has the %s theme", theme)
+	}
+	settings, err = c.UpdateSettings(ctx, api.SettingsPatch{Theme: &theme, Feed: &api.FeedSettingsPatch{Sort: &sortOrder}})
+	check(t, "UpdateSettings", err)
+	if settings.Theme != theme || settings.Feed.Sort != sortOrder {
+		t.Errorf("UpdateSettings = %+v", settings)
+	}
+
+	check(t, "DeleteChannel", c.DeleteChannel(ctx, contractChannelID))
+	checkStatus(t, "DeleteChannel of a removed channel", c.DeleteChannel(ctx, contractChannelID), http.StatusNotFound)
+
+	for _, id := range apiOperationIDs(spec) {
+		if !checker.wasCalled(id) {
+			t.Errorf("operation %s isn't tested", id)
+		}
+	}
+}
+
+func check(t *testing.T, what string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
+
+// checkStatus checks that err is an API error with the given status.
+func checkStatus(t *testing.T, what string, err error, status int) {
+	t.Helper()
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != status {
+		t.Errorf("%s: got %v, want a %d error", what, err, status)
+	}
+}
+
+// apiOperationIDs lists the operations under /api/v1, which the client has
+// a method for each of.
+func apiOperationIDs(spec map[string]any) []string {
+	var ids []string
+	for path, item := range spec["paths"].(map[string]any) {
+		if !strings.HasPrefix(path, "/api/v1/") {
+			continue
+		}
+		for _, operation := range item.(map[string]any) {
+			if id, ok := lookup(operation, "operationId").(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// specChecker passes requests on to the server, checking each request and
+// response against the description and noting the operations called.
+type specChecker struct {
+	t    *testing.T
+	spec map[string]any
+	next http.Handler
+
+	mu     sync.Mutex
+	called map[string]bool
+}
+
+func (c *specChecker) wasCalled(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.called[id]
+}
+
+func (c *specChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.Method + " " + r.URL.RequestURI()
+	specPath, item, operation := findOperation(c.spec, r.Method, r.URL.Path)
+	if operation == nil {
+		c.t.Errorf("%s: not described", name)
+		c.next.ServeHTTP(w, r)
+		return
+	}
+	if id, ok := operation["operationId"].(string); ok {
+		c.mu.Lock()
+		c.called[id] = true
+		c.mu.Unlock()
+	}
+
+	described := make(map[string]bool)
+	for _, parameter := range append(asSlice(item["parameters"]), asSlice(operation["parameters"])...) {
+		parameter := resolve(c.spec, parameter.(map[string]any))
+		if parameter["in"] == "query" {
+			described[parameter["name"].(string)] = true
+		}
+	}
+	for key := range r.URL.Query() {
+		if !described[key] {
+			c.t.Errorf("%s: query parameter %s isn't described", name, key)
+		}
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		c.t.Errorf("%s: reading the body: %v", name, err)
+	}
+	if len(body) > 0 {
+		schema, ok := lookup(operation, "requestBody", "content", "application/json", "schema").(map[string]any)
+		if !ok {
+			c.t.Errorf("%s: has a body, but none is described", name)
+		} else {
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+			var value any
+			if err := decoder.Decode(&value); err != nil {
+				c.t.Errorf("%s: invalid JSON body: %v", name, err)
+			}
+			for _, problem := range checkSchema(c.spec, schema, value, "request") {
+				c.t.Errorf("%s: %s", name, problem)
+			}
+		}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := httptest.NewRecorder()
+	c.next.ServeHTTP(rec, r)
+	response := bytes.Clone(rec.Body.Bytes())
+	for _, problem := range checkResponse(c.spec, specPath, r.Method, rec) {
+		c.t.Errorf("%s: %s", name, problem)
+	}
+
+	for key, values := range rec.Header() {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(rec.Code)
+	w.Write(response)
+}
+
+// specPathVarRegex matches the variables in the description's paths.
+var specPathVarRegex = regexp.MustCompile(`\\\{\w+\\\}`)
+
+// findOperation returns the described operation a request is for, with its
+// path and path item.
+func findOperation(spec map[string]any, method, path string) (string, map[string]any, map[string]any) {
+	for specPath, item := range spec["paths"].(map[string]any) {
+		pattern := "^" + specPathVarRegex.ReplaceAllString(regexp.QuoteMeta(specPath), "[^/]+") + "$"
+		if !regexp.MustCompile(pattern).MatchString(path) {
+			continue
+		}
+		item := item.(map[string]any)
+		if operation, ok := item[strings.ToLower(method)].(map[string]any); ok {
+			return specPath, item, operation
+		}
+	}
+	return "", nil, nil
+}
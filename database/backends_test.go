@@ -0,0 +1,94 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// A testBackend opens new, empty databases of one kind. Tests that run on
+// every backend loop over testBackends.
+type testBackend struct {
+	name string
+	// open returns a function that opens the same empty database every time
+	// it's called, each time as a separate connection pool.
+	open func(t *testing.T) func() *sqlStore
+}
+
+// testBackends returns SQLite, and PostgreSQL if DATABASE_URL points at a
+// PostgreSQL server. Each PostgreSQL database is a schema of its own, dropped
+// when the test ends, so the server's other data isn't touched.
+func testBackends() []testBackend {
+	backends := []testBackend{{name: "sqlite", open: openTestSQLite}}
+	if UsesPostgres() {
+		dsn := os.Getenv("DATABASE_URL")
+		backends = append(backends, testBackend{name: "postgres", open: func(t *testing.T) func() *sqlStore {
+			return openTestPostgres(t, dsn)
+		}})
+	}
+	return backends
+}
+
+// forEachBackend runs a test on every backend, skipping PostgreSQL when
+// DATABASE_URL isn't set to one.
+func forEachBackend(t *testing.T, test func(t *testing.T, open func() *sqlStore)) {
+	backends := testBackends()
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.open(t))
+		})
+	}
+	if len(backends) == 1 {
+		t.Run("postgres", func(t *testing.T) {
+			t.Skip("DATABASE_URL isn't a postgres:// URL")
+		})
+	}
+}
+
+func openTestSQLite(t *testing.T) func() *sqlStore {
+	path := filepath.Join(t.TempDir(), "yt_rss.db")
+	return func() *sqlStore {
+		store, err := OpenSQLite(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.(*sqlStore).db.Close() })
+		return store.(*sqlStore)
+	}
+}
+
+func openTestPostgres(t *testing.T, dsn string) func() *sqlStore {
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("yt_rss_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	query.Set("search_path", schema)
+	parsed.RawQuery = query.Encode()
+
+	return func() *sqlStore {
+		store, err := OpenPostgres(parsed.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.(*sqlStore).db.Close() })
+		return store.(*sqlStore)
+	}
+}
@@ -0,0 +1,246 @@
+package database
+
+import (
+	"strings"
+	"yt_rss2/templates"
+)
+
+func (s *sqlStore) Channels(userID int) ([]templates.Channel, error) {
+	rows, err := s.query(`
+		SELECT id, name, url, alias, note, shorts_mode, hide_live_vods, priority, avatar_url
+		FROM channels WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var channels []templates.Channel
+	for rows.Next() {
+		var channel templates.Channel
+		err := rows.Scan(
+			&channel.ID, &channel.Name, &channel.URL, &channel.Alias, &channel.Note,
+			&channel.ShortsMode, &channel.HideLiveVODs, &channel.Priority, &channel.AvatarURL,
+		)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	return channels, rows.Err()
+}
+
+func (s *sqlStore) ChannelExists(userID int, feedURL string) (bool, error) {
+	var existingID int
+	err := s.queryRow("SELECT id FROM channels WHERE user_id = ? AND url = ?", userID, feedURL).Scan(&existingID)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *sqlStore) AddChannel(userID int, channel templates.Channel) error {
+	return s.inTx(func(tx *sqlTx) error {
+		_, err := insertChannel(tx, userID, channel)
+		return err
+	})
+}
+
+func (s *sqlStore) ImportChannels(userID int, channels []templates.Channel) error {
+	return s.inTx(func(tx *sqlTx) error {
+		// Group IDs by name, so each group is looked up or created once.
+		groupIDs := make(map[string]int64)
+		for _, channel := range channels {
+			channelID, err := insertChannel(tx, userID, channel)
+			if err != nil {
+				return err
+			}
+			if err := addChannelToGroups(tx, userID, channelID, channel.Groups, groupIDs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// insertChannel adds a channel and returns its row ID.
+func insertChannel(tx *sqlTx, userID int, channel templates.Channel) (int64, error) {
+	var channelID int64
+	err := tx.queryRow(`
+		INSERT INTO channels (user_id, name, url, alias, note, shorts_mode, hide_live_vods, priority, avatar_url)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		userID, channel.Name, channel.URL, channel.Alias, channel.Note,
+		channel.ShortsMode, boolInt(channel.HideLiveVODs), channel.Priority, channel.AvatarURL,
+	).Scan(&channelID)
+	return channelID, err
+}
+
+// addChannelToGroups puts a channel into the named groups, creating any that
+// don't exist yet. groupIDs caches name lookups across calls in the same
+// transaction.
+func addChannelToGroups(tx *sqlTx, userID int, channelID int64, names []string, groupIDs map[string]int64) error {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		groupID, ok := groupIDs[name]
+		if !ok {
+			err := tx.queryRow("SELECT id FROM channel_groups WHERE user_id = ? AND name = ?", userID, name).Scan(&groupID)
+			if err == ErrNotFound {
+				err = tx.queryRow("INSERT INTO channel_groups (user_id, name) VALUES (?, ?) RETURNING id", userID, name).Scan(&groupID)
+			}
+			if err != nil {
+				return err
+			}
+			groupIDs[name] = groupID
+		}
+
+		_, err := tx.exec("INSERT INTO channel_group_members (group_id, channel_id) VALUES (?, ?) ON CONFLICT DO NOTHING", groupID, channelID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteChannel(userID int, feedURL string) error {
+	return s.inTx(func(tx *sqlTx) error {
+		_, err := tx.exec(`
+			DELETE FROM channel_group_members
+			WHERE channel_id IN (SELECT id FROM channels WHERE user_id = ? AND url = ?)`, userID, feedURL)
+		if err != nil {
+			return err
+		}
+
+		return changedRow(tx.exec("DELETE FROM channels WHERE user_id = ? AND url = ?", userID, feedURL))
+	})
+}
+
+func (s *sqlStore) UpdateChannelSettings(userID int, channel templates.Channel) error {
+	return changedRow(s.exec(`
+		UPDATE channels SET alias = ?, note = ?, shorts_mode = ?, hide_live_vods = ?, priority = ?
+		WHERE id = ? AND user_id = ?`,
+		channel.Alias, channel.Note, channel.ShortsMode, boolInt(channel.HideLiveVODs), channel.Priority, channel.ID, userID))
+}
+
+func (s *sqlStore) ChannelAvatarURL(userID int, feedURL string) (string, error) {
+	var avatarURL string
+	err := s.queryRow("SELECT avatar_url FROM channels WHERE user_id = ? AND url = ?", userID, feedURL).Scan(&avatarURL)
+	return avatarURL, err
+}
+
+func (s *sqlStore) SetAvatarURL(feedURL, avatarURL string) error {
+	_, err := s.exec("UPDATE channels SET avatar_url = ? WHERE url = ?", avatarURL, feedURL)
+	return err
+}
+
+func (s *sqlStore) Feeds() ([]templates.Channel, error) {
+	rows, err := s.query("SELECT url, MIN(name) FROM channels GROUP BY url")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var channels []templates.Channel
+	for rows.Next() {
+		var channel templates.Channel
+		if err := rows.Scan(&channel.URL, &channel.Name); err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	return channels, rows.Err()
+}
+
+func (s *sqlStore) Groups(userID int) ([]templates.Group, error) {
+	rows, err := s.query("SELECT id, name FROM channel_groups WHERE user_id = ? ORDER BY LOWER(name)", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []templates.Group
+	for rows.Next() {
+		var group templates.Group
+		if err := rows.Scan(&group.ID, &group.Name); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+func (s *sqlStore) ChannelGroups(userID int) (map[int][]string, error) {
+	rows, err := s.query(`
+		SELECT m.channel_id, g.name
+		FROM channel_group_members m
+		JOIN channel_groups g ON g.id = m.group_id
+		WHERE g.user_id = ?
+		ORDER BY LOWER(g.name)`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make(map[int][]string)
+	for rows.Next() {
+		var channelID int
+		var name string
+		if err := rows.Scan(&channelID, &name); err != nil {
+			return nil, err
+		}
+		groups[channelID] = append(groups[channelID], name)
+	}
+	return groups, rows.Err()
+}
+
+func (s *sqlStore) CreateGroup(userID int, name string) error {
+	_, err := s.exec("INSERT INTO channel_groups (user_id, name) VALUES (?, ?)", userID, name)
+	return err
+}
+
+func (s *sqlStore) HasGroup(userID, groupID int) (bool, error) {
+	var count int
+	err := s.queryRow("SELECT COUNT(*) FROM channel_groups WHERE id = ? AND user_id = ?", groupID, userID).Scan(&count)
+	return count > 0, err
+}
+
+func (s *sqlStore) UpdateGroup(userID, groupID int, name string, feedURLs []string) error {
+	return s.inTx(func(tx *sqlTx) error {
+		if err := changedRow(tx.exec("UPDATE channel_groups SET name = ? WHERE id = ? AND user_id = ?", name, groupID, userID)); err != nil {
+			return err
+		}
+		if _, err := tx.exec("DELETE FROM channel_group_members WHERE group_id = ?", groupID); err != nil {
+			return err
+		}
+
+		// Only the user's own channels can be added. The cast tells
+		// PostgreSQL the type of the group ID.
+		for _, feedURL := range feedURLs {
+			_, err := tx.exec(`
+				INSERT INTO channel_group_members (group_id, channel_id)
+				SELECT CAST(? AS BIGINT), id FROM channels WHERE user_id = ? AND url = ?`, groupID, userID, feedURL)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) DeleteGroup(userID, groupID int) error {
+	return s.inTx(func(tx *sqlTx) error {
+		_, err := tx.exec(`
+			DELETE FROM channel_group_members
+			WHERE group_id IN (SELECT id FROM channel_groups WHERE id = ? AND user_id = ?)`, groupID, userID)
+		if err != nil {
+			return err
+		}
+		return changedRow(tx.exec("DELETE FROM channel_groups WHERE id = ? AND user_id = ?", groupID, userID))
+	})
+}
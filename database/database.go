@@ -1,50 +1,44 @@
 package database
 
 import (
-	"database/sql"
 	"log"
-
-	_ "github.com/mattn/go-sqlite3"
+	"os"
+	"strings"
 )
 
-var DB *sql.DB
+// DB is where the app keeps everything. It's SQLite in ./yt_rss.db unless
+// DATABASE_URL says otherwise.
+var DB Store
 
+// InitDB opens the database and brings its schema up to date.
 func InitDB() {
-	var err error
-	DB, err = sql.Open("sqlite3", "./yt_rss.db")
-	if err != nil {
+	Open()
+	if _, err := DB.Migrate(); err != nil {
 		log.Fatal(err)
 	}
-
-	createTables()
 }
 
-func createTables() {
-	usersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		username TEXT NOT NULL UNIQUE,
-		password_hash TEXT NOT NULL,
-		theme TEXT NOT NULL DEFAULT 'rose-pine'
-	);
-	`
-	channelsTable := `
-	CREATE TABLE IF NOT EXISTS channels (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		name TEXT NOT NULL,
-		url TEXT NOT NULL,
-		FOREIGN KEY(user_id) REFERENCES users(id)
-	);
-	`
-
-	_, err := DB.Exec(usersTable)
-	if err != nil {
-		log.Fatal(err)
+// Open opens the database without migrating it. A postgres:// or
+// postgresql:// DATABASE_URL selects PostgreSQL; anything else is the path
+// of a SQLite database.
+func Open() {
+	dsn := os.Getenv("DATABASE_URL")
+	var err error
+	switch {
+	case UsesPostgres():
+		DB, err = OpenPostgres(dsn)
+	case dsn == "":
+		DB, err = OpenSQLite("./yt_rss.db")
+	default:
+		DB, err = OpenSQLite(dsn)
 	}
-
-	_, err = DB.Exec(channelsTable)
 	if err != nil {
 		log.Fatal(err)
 	}
-}
\ No newline at end of file
+}
+
+// UsesPostgres reports whether DATABASE_URL selects PostgreSQL.
+func UsesPostgres() bool {
+	dsn := os.Getenv("DATABASE_URL")
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
@@ -0,0 +1,156 @@
+package database
+
+import "fmt"
+
+// Migration is one step in the schema's history. Migrations are applied in
+// version order, each in its own transaction, and recorded in the
+// schema_migrations table so they only run once.
+type Migration struct {
+	Version int
+	Name    string
+	up      func(tx *sqlTx) error
+}
+
+// migrations is the schema's history. Add new migrations to the end with
+// the next version; never change one that has been released. Statements are
+// written for SQLite and their types rewritten for other databases, see
+// dialect.ddl.
+//
+// Databases created before migrations were tracked can be anywhere up to
+// version 7, so those migrations only create what's missing. Later ones can
+// assume the schema of the version before them.
+var migrations = []Migration{
+	{1, "create users and channels", execStatements(`
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		theme TEXT NOT NULL DEFAULT 'rose-pine'
+	);
+	CREATE TABLE IF NOT EXISTS channels (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		url TEXT NOT NULL,
+		FOREIGN KEY(user_id) REFERENCES users(id)
+	);
+	`)},
+	{2, "add user settings", addColumns("users", [][2]string{
+		{"feed_filter", "TEXT NOT NULL DEFAULT ''"},
+		{"timezone", "TEXT NOT NULL DEFAULT ''"},
+		{"last_seen_at", "INTEGER NOT NULL DEFAULT 0"},
+		{"previous_visit_at", "INTEGER NOT NULL DEFAULT 0"},
+		{"feed_token", "TEXT NOT NULL DEFAULT ''"},
+	})},
+	{3, "add channel settings", addColumns("channels", [][2]string{
+		{"avatar_url", "TEXT NOT NULL DEFAULT ''"},
+		{"alias", "TEXT NOT NULL DEFAULT ''"},
+		{"note", "TEXT NOT NULL DEFAULT ''"},
+		{"shorts_mode", "TEXT NOT NULL DEFAULT ''"},
+		{"hide_live_vods", "INTEGER NOT NULL DEFAULT 0"},
+		{"priority", "INTEGER NOT NULL DEFAULT 0"},
+	})},
+	{4, "create channel groups and saved views", execStatements(`
+	CREATE TABLE IF NOT EXISTS channel_groups (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		UNIQUE(user_id, name),
+		FOREIGN KEY(user_id) REFERENCES users(id)
+	);
+	CREATE TABLE IF NOT EXISTS channel_group_members (
+		group_id INTEGER NOT NULL,
+		channel_id INTEGER NOT NULL,
+		PRIMARY KEY(group_id, channel_id),
+		FOREIGN KEY(group_id) REFERENCES channel_groups(id),
+		FOREIGN KEY(channel_id) REFERENCES channels(id)
+	);
+	CREATE TABLE IF NOT EXISTS saved_views (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		query TEXT NOT NULL,
+		UNIQUE(user_id, name),
+		FOREIGN KEY(user_id) REFERENCES users(id)
+	);
+	`)},
+	{5, "create watch history and watch later", execStatements(`
+	CREATE TABLE IF NOT EXISTS watched_videos (
+		user_id INTEGER NOT NULL,
+		video_id TEXT NOT NULL,
+		watched_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY(user_id, video_id),
+		FOREIGN KEY(user_id) REFERENCES users(id)
+	);
+	CREATE TABLE IF NOT EXISTS watch_later (
+		user_id INTEGER NOT NULL,
+		video_id TEXT NOT NULL,
+		added_at INTEGER NOT NULL,
+		PRIMARY KEY(user_id, video_id),
+		FOREIGN KEY(user_id) REFERENCES users(id)
+	);
+	`)},
+	// Videos and feed_status hold public data about YouTube channels, shared
+	// by every user subscribed to them.
+	{6, "create video store", execStatements(`
+	CREATE TABLE IF NOT EXISTS videos (
+		video_id TEXT PRIMARY KEY,
+		channel_id TEXT NOT NULL,
+		title TEXT NOT NULL,
+		link TEXT NOT NULL,
+		published_at INTEGER NOT NULL,
+		duration_seconds INTEGER NOT NULL DEFAULT 0,
+		views INTEGER NOT NULL DEFAULT 0,
+		was_live INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS videos_channel_published ON videos(channel_id, published_at);
+	CREATE TABLE IF NOT EXISTS feed_status (
+		channel_id TEXT PRIMARY KEY,
+		last_checked_at INTEGER NOT NULL DEFAULT 0,
+		last_success_at INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		consecutive_failures INTEGER NOT NULL DEFAULT 0
+	);
+	`)},
+	// API tokens are stored hashed, as they're only shown once.
+	{7, "create api tokens", execStatements(`
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		created_at INTEGER NOT NULL,
+		last_used_at INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY(user_id) REFERENCES users(id)
+	);
+	`)},
+}
+
+// execStatements returns a migration step that runs schema statements.
+func execStatements(statements string) func(tx *sqlTx) error {
+	return func(tx *sqlTx) error {
+		_, err := tx.tx.Exec(tx.dialect.ddl(statements))
+		return err
+	}
+}
+
+// addColumns returns a migration step that adds columns to a table, skipping
+// any it already has.
+func addColumns(table string, columns [][2]string) func(tx *sqlTx) error {
+	return func(tx *sqlTx) error {
+		existing, err := tx.dialect.columns(tx, table)
+		if err != nil {
+			return err
+		}
+		for _, column := range columns {
+			if existing[column[0]] {
+				continue
+			}
+			_, err := tx.tx.Exec(tx.dialect.ddl(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column[0], column[1])))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"sync"
+	"testing"
+)
+
+// baselineSchema is the schema databases had before migrations were
+// tracked, as the app first created it.
+const baselineSchema = `
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	theme TEXT NOT NULL DEFAULT 'rose-pine'
+);
+CREATE TABLE IF NOT EXISTS channels (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	url TEXT NOT NULL,
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+`
+
+func latestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+func TestMigrateFromBaseline(t *testing.T) {
+	store := openTestSQLite(t)()
+	if _, err := store.db.Exec(baselineSchema); err != nil {
+		t.Fatal(err)
+	}
+	_, err := store.db.Exec(`
+		INSERT INTO users (id, username, password_hash) VALUES (1, 'alice', 'hash');
+		INSERT INTO channels (user_id, name, url) VALUES (1, 'Alpha', 'https://www.youtube.com/feeds/videos.xml?channel_id=UCabcdefghijklmnopqrstuv');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := store.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("applied %d migrations, want %d", len(applied), len(migrations))
+	}
+	if version, err := store.SchemaVersion(); err != nil || version != latestVersion() {
+		t.Errorf("SchemaVersion() = %d, %v, want %d", version, err, latestVersion())
+	}
+
+	channels, err := store.Channels(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 1 || channels[0].Name != "Alpha" || channels[0].ShortsMode != "" || channels[0].Priority != 0 {
+		t.Errorf("channels after migrating = %+v, want Alpha with default settings", channels)
+	}
+	if filter, err := store.FeedFilter(1); err != nil || filter != "" {
+		t.Errorf("FeedFilter() = %q, %v, want the default", filter, err)
+	}
+
+	applied, err = store.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("migrating again applied %d migrations, want none", len(applied))
+	}
+	if version, err := store.SchemaVersion(); err != nil || version != latestVersion() {
+		t.Errorf("SchemaVersion() after migrating again = %d, %v, want %d", version, err, latestVersion())
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() *sqlStore) {
+		store := open()
+		applied, err := store.Migrate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(applied) != len(migrations) {
+			t.Errorf("applied %d migrations, want %d", len(applied), len(migrations))
+		}
+
+		applied, err = store.Migrate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(applied) != 0 {
+			t.Errorf("migrating again applied %d migrations, want none", len(applied))
+		}
+		if version, err := store.SchemaVersion(); err != nil || version != latestVersion() {
+			t.Errorf("SchemaVersion() = %d, %v, want %d", version, err, latestVersion())
+		}
+	})
+}
+
+func TestMigrateConcurrently(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() *sqlStore) {
+		stores := []*sqlStore{open(), open(), open()}
+
+		var wg sync.WaitGroup
+		applied := make([][]Migration, len(stores))
+		errs := make([]error, len(stores))
+		for i, store := range stores {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				applied[i], errs[i] = store.Migrate()
+			}()
+		}
+		wg.Wait()
+
+		total := 0
+		for i := range stores {
+			if errs[i] != nil {
+				t.Errorf("Migrate() in process %d: %v", i, errs[i])
+			}
+			total += len(applied[i])
+		}
+		if total != len(migrations) {
+			t.Errorf("applied %d migrations between them, want each of the %d once", total, len(migrations))
+		}
+		if version, err := stores[0].SchemaVersion(); err != nil || version != latestVersion() {
+			t.Errorf("SchemaVersion() = %d, %v, want %d", version, err, latestVersion())
+		}
+	})
+}
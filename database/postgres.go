@@ -0,0 +1,90 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// OpenPostgres connects to the PostgreSQL database at a postgres:// URL.
+func OpenPostgres(dsn string) (Store, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStore{db: db, dialect: postgresDialect{}}, nil
+}
+
+type postgresDialect struct{}
+
+// rebind numbers the placeholders, as in $1, skipping string literals.
+func (postgresDialect) rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	inString := false
+	for _, c := range query {
+		switch {
+		case c == '\'':
+			inString = !inString
+		case c == '?' && !inString:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Unix times don't fit in a PostgreSQL INTEGER after 2038, so every integer
+// column is a BIGINT.
+var postgresTypes = strings.NewReplacer(
+	"INTEGER PRIMARY KEY AUTOINCREMENT", "BIGSERIAL PRIMARY KEY",
+	"INTEGER", "BIGINT",
+	"DATETIME", "TIMESTAMP",
+)
+
+func (postgresDialect) ddl(statements string) string {
+	return postgresTypes.Replace(statements)
+}
+
+func (postgresDialect) columns(tx *sqlTx, table string) (map[string]bool, error) {
+	rows, err := tx.query(`
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ?`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+func (postgresDialect) isDuplicate(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// migrationLockID identifies the advisory lock held while migrating. Any
+// number works, as long as nothing else uses it.
+const migrationLockID = 0x79745f727373
+
+func (postgresDialect) lockMigrations(tx *sqlTx) error {
+	_, err := tx.exec("SELECT pg_advisory_xact_lock(?)", migrationLockID)
+	return err
+}
+
+func (postgresDialect) backup(db *sql.DB, path string) error {
+	return errors.New("back up a PostgreSQL database with pg_dump")
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// sqlStore is the Store for SQL databases. Queries are written once, with ?
+// placeholders and SQL that SQLite and PostgreSQL both understand, and the
+// dialect covers the rest.
+type sqlStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// dialect is what differs between the databases sqlStore runs on.
+type dialect interface {
+	// rebind rewrites the ? placeholders in a query to the database's own.
+	rebind(query string) string
+	// ddl rewrites a schema statement written for SQLite to the database's
+	// own types.
+	ddl(statements string) string
+	// columns returns the names of a table's columns.
+	columns(tx *sqlTx, table string) (map[string]bool, error)
+	// isDuplicate reports whether an error is a unique constraint failing.
+	isDuplicate(err error) bool
+	// lockMigrations makes other processes wait to migrate until tx ends.
+	lockMigrations(tx *sqlTx) error
+	backup(db *sql.DB, path string) error
+}
+
+// sqlTx is a transaction that rebinds its queries like sqlStore does.
+type sqlTx struct {
+	tx      *sql.Tx
+	dialect dialect
+}
+
+func (s *sqlStore) exec(query string, args ...any) (sql.Result, error) {
+	result, err := s.db.Exec(s.dialect.rebind(query), args...)
+	return result, s.checkDuplicate(err)
+}
+
+func (s *sqlStore) query(query string, args ...any) (*sql.Rows, error) {
+	return s.db.Query(s.dialect.rebind(query), args...)
+}
+
+func (s *sqlStore) queryRow(query string, args ...any) row {
+	return row{s.db.QueryRow(s.dialect.rebind(query), args...)}
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds.
+func (s *sqlStore) inTx(fn func(tx *sqlTx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&sqlTx{tx: tx, dialect: s.dialect}); err != nil {
+		return s.checkDuplicate(err)
+	}
+	return tx.Commit()
+}
+
+// checkDuplicate wraps unique constraint failures in ErrDuplicate.
+func (s *sqlStore) checkDuplicate(err error) error {
+	if err != nil && s.dialect.isDuplicate(err) {
+		return fmt.Errorf("%w: %v", ErrDuplicate, err)
+	}
+	return err
+}
+
+func (tx *sqlTx) exec(query string, args ...any) (sql.Result, error) {
+	return tx.tx.Exec(tx.dialect.rebind(query), args...)
+}
+
+func (tx *sqlTx) query(query string, args ...any) (*sql.Rows, error) {
+	return tx.tx.Query(tx.dialect.rebind(query), args...)
+}
+
+func (tx *sqlTx) queryRow(query string, args ...any) row {
+	return row{tx.tx.QueryRow(tx.dialect.rebind(query), args...)}
+}
+
+// row is a query result of one row, which returns ErrNotFound if there's
+// none.
+type row struct {
+	row *sql.Row
+}
+
+func (r row) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+// changedRow returns ErrNotFound if an update or delete didn't match any
+// row.
+func changedRow(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if changed == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) Migrate() ([]Migration, error) {
+	err := s.inTx(func(tx *sqlTx) error {
+		if err := tx.dialect.lockMigrations(tx); err != nil {
+			return err
+		}
+		_, err := tx.tx.Exec(tx.dialect.ddl(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at INTEGER NOT NULL
+		);
+		`))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range migrations {
+		if migration.Version <= version {
+			continue
+		}
+		ran := false
+		err := s.inTx(func(tx *sqlTx) error {
+			// Another process may have applied the migration while this one
+			// waited for the lock.
+			if err := tx.dialect.lockMigrations(tx); err != nil {
+				return err
+			}
+			var current int
+			if err := tx.queryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+				return err
+			}
+			if current >= migration.Version {
+				return nil
+			}
+
+			if err := migration.up(tx); err != nil {
+				return err
+			}
+			_, err := tx.exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now().Unix())
+			ran = err == nil
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+		if ran {
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+func (s *sqlStore) SchemaVersion() (int, error) {
+	var version int
+	err := s.queryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+func (s *sqlStore) Backup(path string) error {
+	return s.dialect.backup(s.db, path)
+}
+
+// boolInt stores a bool as 0 or 1, as the schema has no boolean columns.
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
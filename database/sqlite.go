@@ -0,0 +1,77 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// OpenSQLite opens the SQLite database at path, creating it if needed.
+// Transactions take the write lock when they begin, so two of them never
+// fail trying to upgrade their read locks at once.
+func OpenSQLite(path string) (Store, error) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	db, err := sql.Open("sqlite3", path+separator+"_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStore{db: db, dialect: sqliteDialect{}}, nil
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) rebind(query string) string {
+	return query
+}
+
+func (sqliteDialect) ddl(statements string) string {
+	return statements
+}
+
+func (sqliteDialect) columns(tx *sqlTx, table string) (map[string]bool, error) {
+	rows, err := tx.query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+func (sqliteDialect) isDuplicate(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+// lockMigrations has nothing to do, as every transaction holds the write
+// lock.
+func (sqliteDialect) lockMigrations(tx *sqlTx) error {
+	return nil
+}
+
+func (sqliteDialect) backup(db *sql.DB, path string) error {
+	_, err := db.Exec("VACUUM INTO ?", path)
+	return err
+}
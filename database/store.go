@@ -0,0 +1,199 @@
+package database
+
+import (
+	"errors"
+	"time"
+	"yt_rss2/templates"
+)
+
+// Store is everything the app keeps. Lookups that find nothing return
+// ErrNotFound, as do changes to rows that don't exist or belong to another
+// user. Writes that break a uniqueness rule, like a second group with the
+// same name, return an error wrapping ErrDuplicate.
+type Store interface {
+	// Migrate applies the migrations the database hasn't had yet and
+	// returns them. It stops at the first one that fails, leaving the
+	// database at the version before it.
+	Migrate() ([]Migration, error)
+	// SchemaVersion returns the version of the last migration applied to
+	// the database, or 0 if there's none.
+	SchemaVersion() (int, error)
+	// Backup writes a consistent copy of the database to path, which
+	// mustn't exist yet. It's safe to run while the server is using the
+	// database.
+	Backup(path string) error
+
+	CreateUser(username, passwordHash string) error
+	UserByID(userID int) (templates.User, error)
+	// UserByName also returns the user's password hash, for logging in.
+	UserByName(username string) (templates.User, string, error)
+	Users() ([]UserSummary, error)
+	SetPassword(userID int, passwordHash string) error
+	// DeleteUser deletes a user along with their channels, groups, views,
+	// tokens and watch history. Stored videos are shared, so they stay.
+	DeleteUser(userID int) error
+	SetTheme(userID int, theme string) error
+	// FeedFilter returns the user's saved feed options, as a query string.
+	FeedFilter(userID int) (string, error)
+	SetFeedFilter(userID int, filter string) error
+	// SaveSettings sets the theme, timezone and feed options together.
+	SaveSettings(userID int, theme, timezone, filter string) error
+	// VisitInfo returns the user's timezone and the Unix time their previous
+	// visit ended, 0 if they haven't been before.
+	VisitInfo(userID int) (string, int64, error)
+	SetTimezone(userID int, timezone string) error
+	// RecordVisit notes that the user is active at now. If they were away
+	// for longer than gap, their last visit becomes the previous one.
+	RecordVisit(userID int, now time.Time, gap time.Duration) error
+	// FeedToken returns the user's feed token, empty if they have none.
+	FeedToken(userID int) (string, error)
+	SetFeedToken(userID int, token string) error
+	UserByFeedToken(token string) (templates.User, error)
+
+	CreateAPIToken(userID int, name, tokenHash string, created time.Time) error
+	// APITokens lists the user's tokens, oldest first.
+	APITokens(userID int) ([]APIToken, error)
+	DeleteAPIToken(userID, tokenID int) error
+	// UseAPIToken returns the user a token belongs to and notes that it was
+	// used at now.
+	UseAPIToken(tokenHash string, now time.Time) (templates.User, error)
+
+	// Channels returns the user's channels. Only the stored fields are
+	// set, so ChannelID and Groups are left empty.
+	Channels(userID int) ([]templates.Channel, error)
+	ChannelExists(userID int, feedURL string) (bool, error)
+	// AddChannel subscribes the user to a channel with its settings. Groups
+	// are ignored.
+	AddChannel(userID int, channel templates.Channel) error
+	// ImportChannels subscribes the user to channels along with their
+	// groups, creating any groups that don't exist yet. It's all or
+	// nothing.
+	ImportChannels(userID int, channels []templates.Channel) error
+	// DeleteChannel unsubscribes the user from a feed, taking it out of
+	// their groups too.
+	DeleteChannel(userID int, feedURL string) error
+	// UpdateChannelSettings saves the alias, note and display preferences
+	// of the user's channel with channel.ID.
+	UpdateChannelSettings(userID int, channel templates.Channel) error
+	ChannelAvatarURL(userID int, feedURL string) (string, error)
+	// SetAvatarURL sets the avatar of a feed for every user subscribed to
+	// it.
+	SetAvatarURL(feedURL, avatarURL string) error
+	// Feeds returns every feed any user is subscribed to, once, with its
+	// URL and a name.
+	Feeds() ([]templates.Channel, error)
+
+	// Groups returns the user's groups, sorted by name.
+	Groups(userID int) ([]templates.Group, error)
+	// ChannelGroups maps each of the user's channel row IDs to the names of
+	// the groups it belongs to.
+	ChannelGroups(userID int) (map[int][]string, error)
+	CreateGroup(userID int, name string) error
+	// HasGroup reports whether a group ID belongs to the user.
+	HasGroup(userID, groupID int) (bool, error)
+	// UpdateGroup renames one of the user's groups and replaces its
+	// channels with the user's channels that have the given feed URLs.
+	UpdateGroup(userID, groupID int, name string, feedURLs []string) error
+	// DeleteGroup removes one of the user's groups. Its channels stay
+	// subscribed.
+	DeleteGroup(userID, groupID int) error
+
+	CreateView(userID int, name, query string) error
+	// View returns one of the user's saved views.
+	View(userID, viewID int) (templates.SavedView, error)
+	// Views returns the user's saved views in the order they were made.
+	Views(userID int) ([]templates.SavedView, error)
+	// UpdateView saves the name and query of the user's view with view.ID.
+	UpdateView(userID int, view templates.SavedView) error
+	DeleteView(userID, viewID int) error
+
+	// WatchedVideoIDs lists the videos the user has watched, most recently
+	// watched first.
+	WatchedVideoIDs(userID int) ([]string, error)
+	SetWatched(userID int, videoID string, watched bool) error
+	InWatchLater(userID int, videoID string) (bool, error)
+	SetWatchLater(userID int, videoID string, queued bool, now time.Time) error
+	// WatchLater returns the user's Watch Later queue, oldest first.
+	WatchLater(userID int) ([]WatchLaterEntry, error)
+
+	// StoreVideos saves fetched videos. Durations, views and whether a
+	// video was live are only overwritten when known.
+	StoreVideos(videos []Video) error
+	// Videos returns a page of a channel's stored uploads, newest first.
+	Videos(channelID string, limit, offset int) ([]Video, error)
+	// VideosByID returns the stored videos with the given IDs, newest first.
+	// IDs that aren't stored are left out.
+	VideosByID(videoIDs []string) ([]Video, error)
+	VideoStats(channelID string) (VideoStats, error)
+	// RecordFeedFetch keeps track of how fetching a channel's feed went.
+	// fetchErr is nil for a successful fetch.
+	RecordFeedFetch(channelID string, fetchErr error, now time.Time) error
+	FeedStatus(channelID string) (FeedStatus, error)
+}
+
+// ErrNotFound is returned for rows that don't exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrDuplicate is wrapped by errors from writes that would duplicate a
+// unique value.
+var ErrDuplicate = errors.New("already exists")
+
+// UserSummary is a user as the user list shows them.
+type UserSummary struct {
+	ID       int
+	Username string
+	Channels int
+}
+
+// APIToken is a personal access token. The token itself is only stored
+// hashed. Times are Unix seconds, and LastUsedAt is 0 for unused tokens.
+type APIToken struct {
+	ID         int
+	Name       string
+	CreatedAt  int64
+	LastUsedAt int64
+}
+
+// Video is a stored upload. PublishedAt is in Unix seconds, and
+// DurationSeconds and Views are 0 when unknown.
+type Video struct {
+	VideoID         string
+	ChannelID       string
+	Title           string
+	Link            string
+	PublishedAt     int64
+	DurationSeconds int64
+	Views           int64
+	WasLive         bool
+}
+
+// VideoStats summarises a channel's stored uploads. The times are 0 if
+// there are none.
+type VideoStats struct {
+	Uploads int
+	Oldest  int64
+	Newest  int64
+}
+
+// FeedStatus is how fetching a channel's feed has been going. Times are
+// Unix seconds.
+type FeedStatus struct {
+	LastCheckedAt int64
+	LastSuccessAt int64
+	LastError     string
+	// Failures counts the fetches that failed since the last success.
+	Failures int
+}
+
+// WatchLaterEntry is a video in a Watch Later queue. Details come from the
+// stored uploads and the user's channels, and are empty for videos that
+// aren't stored.
+type WatchLaterEntry struct {
+	VideoID         string
+	Title           string
+	PublishedAt     int64
+	DurationSeconds int64
+	ChannelID       string
+	ChannelName     string
+	ChannelAlias    string
+}
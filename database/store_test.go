@@ -0,0 +1,631 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+	"yt_rss2/templates"
+)
+
+// These tests are the contract every Store implementation keeps. They run on
+// each backend from testBackends.
+
+const (
+	feedA = "https://www.youtube.com/feeds/videos.xml?channel_id=UCaaaaaaaaaaaaaaaaaaaaaa"
+	feedB = "https://www.youtube.com/feeds/videos.xml?channel_id=UCbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+// newTestStore returns a migrated, empty store.
+func newTestStore(t *testing.T, open func() *sqlStore) Store {
+	t.Helper()
+	store := open()
+	if _, err := store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// createUser adds a user and returns them.
+func createUser(t *testing.T, store Store, username string) templates.User {
+	t.Helper()
+	if err := store.CreateUser(username, "hash-"+username); err != nil {
+		t.Fatal(err)
+	}
+	user, _, err := store.UserByName(username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// channelByURL returns one of the user's channels, failing the test if
+// they don't have it.
+func channelByURL(t *testing.T, store Store, userID int, feedURL string) templates.Channel {
+	t.Helper()
+	channels, err := store.Channels(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, channel := range channels {
+		if channel.URL == feedURL {
+			return channel
+		}
+	}
+	t.Fatalf("user %d has no channel %s", userID, feedURL)
+	return templates.Channel{}
+}
+
+func check(t *testing.T, what string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
+
+func checkNotFound(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("%s: got %v, want ErrNotFound", what, err)
+	}
+}
+
+func checkDuplicate(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, ErrDuplicate) {
+		t.Errorf("%s: got %v, want ErrDuplicate", what, err)
+	}
+}
+
+func TestStoreUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() *sqlStore) {
+		store := newTestStore(t, open)
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+
+		checkDuplicate(t, "CreateUser with a taken name", store.CreateUser("alice", "other"))
+
+		user, hash, err := store.UserByName("alice")
+		check(t, "UserByName", err)
+		if user != alice || hash != "hash-alice" || user.Theme != "rose-pine" {
+			t.Errorf("UserByName = %+v, %q", user, hash)
+		}
+		_, _, err = store.UserByName("carol")
+		checkNotFound(t, "UserByName of a missing user", err)
+
+		user, err = store.UserByID(bob.ID)
+		check(t, "UserByID", err)
+		if user != bob {
+			t.Errorf("UserByID = %+v, want %+v", user, bob)
+		}
+		_, err = store.UserByID(-1)
+		checkNotFound(t, "UserByID of a missing user", err)
+
+		check(t, "SetPassword", store.SetPassword(alice.ID, "new-hash"))
+		if _, hash, _ := store.UserByName("alice"); hash != "new-hash" {
+			t.Errorf("password hash after SetPassword = %q", hash)
+		}
+		checkNotFound(t, "SetPassword of a missing user", store.SetPassword(-1, "hash"))
+
+		check(t, "SetTheme", store.SetTheme(alice.ID, "nord"))
+		if user, _ := store.UserByID(alice.ID); user.Theme != "nord" {
+			t.Errorf("theme after SetTheme = %q", user.Theme)
+		}
+		checkNotFound(t, "SetTheme of a missing user", store.SetTheme(-1, "nord"))
+
+		check(t, "SetFeedFilter", store.SetFeedFilter(alice.ID, "sort=oldest"))
+		filter, err := store.FeedFilter(alice.ID)
+		check(t, "FeedFilter", err)
+		if filter != "sort=oldest" {
+			t.Errorf("FeedFilter = %q", filter)
+		}
+
+		check(t, "SetTimezone", store.SetTimezone(alice.ID, "Europe/Berlin"))
+		timezone, previousVisit, err := store.VisitInfo(alice.ID)
+		check(t, "VisitInfo", err)
+		if timezone != "Europe/Berlin" || previousVisit != 0 {
+			t.Errorf("VisitInfo = %q, %d", timezone, previousVisit)
+		}
+
+		check(t, "SaveSettings", store.SaveSettings(alice.ID, "gruvbox", "Asia/Tokyo", "show-shorts=true"))
+		user, _ = store.UserByID(alice.ID)
+		filter, _ = store.FeedFilter(alice.ID)
+		timezone, _, _ = store.VisitInfo(alice.ID)
+		if user.Theme != "gruvbox" || timezone != "Asia/Tokyo" || filter != "show-shorts=true" {
+			t.Errorf("after SaveSettings: theme %q, timezone %q, filter %q", user.Theme, timezone, filter)
+		}
+
+		// A visit only becomes the previous one after a gap.
+		start := time.Unix(1_800_000_000, 0)
+		gap := time.Hour
+		check(t, "RecordVisit", store.RecordVisit(alice.ID, start, gap))
+		check(t, "RecordVisit", store.RecordVisit(alice.ID, start.Add(time.Minute), gap))
+		if _, previousVisit, _ := store.VisitInfo(alice.ID); previousVisit != 0 {
+			t.Errorf("previous visit without a gap = %d, want 0", previousVisit)
+		}
+		check(t, "RecordVisit", store.RecordVisit(alice.ID, start.Add(2*time.Hour), gap))
+		if _, previousVisit, _ := store.VisitInfo(alice.ID); previousVisit != start.Add(time.Minute).Unix() {
+			t.Errorf("previous visit after a gap = %d, want %d", previousVisit, start.Add(time.Minute).Unix())
+		}
+
+		token, err := store.FeedToken(alice.ID)
+		check(t, "FeedToken", err)
+		if token != "" {
+			t.Errorf("FeedToken before setting one = %q", token)
+		}
+		check(t, "SetFeedToken", store.SetFeedToken(alice.ID, "feed-token"))
+		if token, _ := store.FeedToken(alice.ID); token != "feed-token" {
+			t.Errorf("FeedToken = %q", token)
+		}
+		checkNotFound(t, "SetFeedToken of a missing user", store.SetFeedToken(-1, "other-token"))
+		user, err = store.UserByFeedToken("feed-token")
+		check(t, "UserByFeedToken", err)
+		if user.ID != alice.ID {
+			t.Errorf("UserByFeedToken = %+v", user)
+		}
+		_, err = store.UserByFeedToken("other-token")
+		checkNotFound(t, "UserByFeedToken of an unknown token", err)
+
+		check(t, "AddChannel", store.AddChannel(bob.ID, templates.Channel{Name: "A", URL: feedA}))
+		users, err := store.Users()
+		check(t, "Users", err)
+		want := []UserSummary{{alice.ID, "alice", 0}, {bob.ID, "bob", 1}}
+		if !slices.Equal(users, want) {
+			t.Errorf("Users = %+v, want %+v", users, want)
+		}
+
+		// Deleting a user takes everything they saved with them.
+		check(t, "CreateGroup", store.CreateGroup(bob.ID, "Tech"))
+		check(t, "CreateView", store.CreateView(bob.ID, "Long", "duration:>20m"))
+		check(t, "SetWatched", store.SetWatched(bob.ID, "vid00000001", true))
+		check(t, "SetWatchLater", store.SetWatchLater(bob.ID, "vid00000001", true, start))
+		check(t, "CreateAPIToken", store.CreateAPIToken(bob.ID, "script", "bob-hash", start))
+		check(t, "DeleteUser", store.DeleteUser(bob.ID))
+
+		_, err = store.UserByID(bob.ID)
+		checkNotFound(t, "UserByID of a deleted user", err)
+		channels, _ := store.Channels(bob.ID)
+		groups, _ := store.Groups(bob.ID)
+		views, _ := store.Views(bob.ID)
+		watched, _ := store.WatchedVideoIDs(bob.ID)
+		queue, _ := store.WatchLater(bob.ID)
+		tokens, _ := store.APITokens(bob.ID)
+		if len(channels)+len(groups)+len(views)+len(watched)+len(queue)+len(tokens) > 0 {
+			t.Errorf("a deleted user still has %d channels, %d groups, %d views, %d watched, %d queued and %d tokens",
+				len(channels), len(groups), len(views), len(watched), len(queue), len(tokens))
+		}
+		// The name is free again.
+		check(t, "CreateUser with a deleted user's name", store.CreateUser("bob", "hash"))
+	})
+}
+
+func TestStoreAPITokens(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() *sqlStore) {
+		store := newTestStore(t, open)
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+
+		created := time.Unix(1_800_000_000, 0)
+		check(t, "CreateAPIToken", store.CreateAPIToken(alice.ID, "first", "hash-1", created))
+		check(t, "CreateAPIToken", store.CreateAPIToken(alice.ID, "second", "hash-2", created.Add(time.Minute)))
+		checkDuplicate(t, "CreateAPIToken with a taken hash", store.CreateAPIToken(bob.ID, "copy", "hash-1", created))
+
+		tokens, err := store.APITokens(alice.ID)
+		check(t, "APITokens", err)
+		if len(tokens) != 2 || tokens[0].Name != "first" || tokens[1].Name != "second" ||
+			tokens[0].CreatedAt != created.Unix() || tokens[0].LastUsedAt != 0 {
+			t.Fatalf("APITokens = %+v", tokens)
+		}
+
+		used := created.Add(time.Hour)
+		user, err := store.UseAPIToken("hash-1", used)
+		check(t, "UseAPIToken", err)
+		if user != alice {
+			t.Errorf("UseAPIToken = %+v, want %+v", user, alice)
+		}
+		if tokens, _ := store.APITokens(alice.ID); tokens[0].LastUsedAt != used.Unix() {
+			t.Errorf("LastUsedAt after UseAPIToken = %d, want %d", tokens[0].LastUsedAt, used.Unix())
+		}
+		_, err = store.UseAPIToken("hash-unknown", used)
+		checkNotFound(t, "UseAPIToken of an unknown token", err)
+
+		// Only the owner can delete a token.
+		checkNotFound(t, "DeleteAPIToken as another user", store.DeleteAPIToken(bob.ID, tokens[0].ID))
+		if tokens, _ := store.APITokens(alice.ID); len(tokens) != 2 {
+			t.Errorf("another user deleted a token")
+		}
+		check(t, "DeleteAPIToken", store.DeleteAPIToken(alice.ID, tokens[0].ID))
+		_, err = store.UseAPIToken("hash-1", used)
+		checkNotFound(t, "UseAPIToken of a deleted token", err)
+		checkNotFound(t, "DeleteAPIToken of a deleted token", store.DeleteAPIToken(alice.ID, tokens[0].ID))
+	})
+}
+
+func TestStoreChannels(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() *sqlStore) {
+		store := newTestStore(t, open)
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+
+		added := templates.Channel{
+			Name: "Alpha", URL: feedA, Alias: "A", Note: "note", ShortsMode: templates.ShortsNever,
+			HideLiveVODs: true, Priority: 2, AvatarURL: "https://yt3.ggpht.com/a.jpg",
+		}
+		check(t, "AddChannel", store.AddChannel(alice.ID, added))
+		got := channelByURL(t, store, alice.ID, feedA)
+		added.ID = got.ID
+		if got.Name != added.Name || got.Alias != added.Alias || got.Note != added.Note || got.ShortsMode != added.ShortsMode ||
+			got.HideLiveVODs != added.HideLiveVODs || got.Priority != added.Priority || got.AvatarURL != added.AvatarURL {
+			t.Errorf("Channels = %+v, want %+v", got, added)
+		}
+
+		exists, err := store.ChannelExists(alice.ID, feedA)
+		check(t, "ChannelExists", err)
+		if !exists {
+			t.Error("ChannelExists = false for a subscribed channel")
+		}
+		if exists, _ := store.ChannelExists(bob.ID, feedA); exists {
+			t.Error("ChannelExists = true for another user's channel")
+		}
+
+		// Imports bring their groups, sharing the ones that exist.
+		check(t, "CreateGroup", store.CreateGroup(bob.ID, "Tech"))
+		err = store.ImportChannels(bob.ID, []templates.Channel{
+			{Name: "Alpha", URL: feedA, Groups: []string{"Tech", " Music "}},
+			{Name: "Beta", URL: feedB, Groups: []string{"Music", ""}},
+		})
+		check(t, "ImportChannels", err)
+		groups, err := store.Groups(bob.ID)
+		check(t, "Groups", err)
+		if len(groups) != 2 || groups[0].Name != "Music" || groups[1].Name != "Tech" {
+			t.Errorf("Groups after ImportChannels = %+v", groups)
+		}
+		channelGroups, err := store.ChannelGroups(bob.ID)
+		check(t, "ChannelGroups", err)
+		bobA := channelByURL(t, store, bob.ID, feedA)
+		bobB := channelByURL(t, store, bob.ID, feedB)
+		if !slices.Equal(channelGroups[bobA.ID], []string{"Music", "Tech"}) || !slices.Equal(channelGroups[bobB.ID], []string{"Music"}) {
+			t.Errorf("ChannelGroups = %v", channelGroups)
+		}
+
+		// Settings can only be changed by the channel's owner.
+		changed := added
+		changed.Alias, changed.Note, changed.ShortsMode, changed.HideLiveVODs, changed.Priority = "", "", templates.ShortsAlways, false, 0
+		checkNotFound(t, "UpdateChannelSettings of another user's channel", store.UpdateChannelSettings(bob.ID, changed))
+		if got := channelByURL(t, store, alice.ID, feedA); got.Alias != "A" {
+			t.Errorf("another user changed a channel's alias to %q", got.Alias)
+		}
+		check(t, "UpdateChannelSettings", store.UpdateChannelSettings(alice.ID, changed))
+		got = channelByURL(t, store, alice.ID, feedA)
+		if got.Alias != "" || got.Note != "" || got.ShortsMode != templates.ShortsAlways || got.HideLiveVODs || got.Priority != 0 {
+			t.Errorf("after UpdateChannelSettings = %+v", got)
+		}
+
+		avatarURL, err := store.ChannelAvatarURL(alice.ID, feedA)
+		check(t, "ChannelAvatarURL", err)
+		if avatarURL != added.AvatarURL {
+			t.Errorf("ChannelAvatarURL = %q", avatarURL)
+		}
+		_, err = store.ChannelAvatarURL(alice.ID, feedB)
+		checkNotFound(t, "ChannelAvatarURL of an unsubscribed channel", err)
+		check(t, "SetAvatarURL", store.SetAvatarURL(feedA, "https://yt3.ggpht.com/new.jpg"))
+		for _, user := range []templates.User{alice, bob} {
+			if avatarURL, _ := store.ChannelAvatarURL(user.ID, feedA); avatarURL != "https://yt3.ggpht.com/new.jpg" {
+				t.Errorf("user %s's avatar after SetAvatarURL = %q", user.Username, avatarURL)
+			}
+		}
+
+		feeds, err := store.Feeds()
+		check(t, "Feeds", err)
+		var feedURLs []string
+		for _, feed := range feeds {
+			feedURLs = append(feedURLs, feed.URL)
+		}
+		slices.Sort(feedURLs)
+		if !slices.Equal(feedURLs, []string{feedA, feedB}) {
+			t.Errorf("Feeds = %v, want each feed once", feedURLs)
+		}
+
+		check(t, "DeleteChannel", store.DeleteChannel(bob.ID, feedA))
+		if exists, _ := store.ChannelExists(bob.ID, feedA); exists {
+			t.Error("ChannelExists = true after DeleteChannel")
+		}
+		if exists, _ := store.ChannelExists(alice.ID, feedA); !exists {
+			t.Error("DeleteChannel removed another user's channel")
+		}
+		checkNotFound(t, "DeleteChannel of a deleted channel", store.DeleteChannel(bob.ID, feedA))
+		channelGroups, _ = store.ChannelGroups(bob.ID)
+		if _, ok := channelGroups[bobA.ID]; ok {
+			t.Error("a deleted channel is still in its groups")
+		}
+	})
+}
+
+func TestStoreGroups(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() *sqlStore) {
+		store := newTestStore(t, open)
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+		check(t, "AddChannel", store.AddChannel(alice.ID, templates.Channel{Name: "Alpha", URL: feedA}))
+		check(t, "AddChannel", store.AddChannel(alice.ID, templates.Channel{Name: "Beta", URL: feedB}))
+		check(t, "AddChannel", store.AddChannel(bob.ID, templates.Channel{Name: "Beta", URL: feedB}))
+
+		check(t, "CreateGroup", store.CreateGroup(alice.ID, "tech"))
+		check(t, "CreateGroup", store.CreateGroup(alice.ID, "Music"))
+		checkDuplicate(t, "CreateGroup with a taken name", store.CreateGroup(alice.ID, "tech"))
+		check(t, "CreateGroup with another user's group name", store.CreateGroup(bob.ID, "tech"))
+
+		groups, err := store.Groups(alice.ID)
+		check(t, "Groups", err)
+		if len(groups) != 2 || groups[0].Name != "Music" || groups[1].Name != "tech" {
+			t.Fatalf("Groups = %+v, want them sorted by name", groups)
+		}
+		tech := groups[1].ID
+		bobGroups, _ := store.Groups(bob.ID)
+		bobTech := bobGroups[0].ID
+
+		has, err := store.HasGroup(alice.ID, tech)
+		check(t, "HasGroup", err)
+		if !has {
+			t.Error("HasGroup = false for the user's group")
+		}
+		if has, _ := store.HasGroup(bob.ID, tech); has {
+			t.Error("HasGroup = true for another user's group")
+		}
+
+		check(t, "UpdateGroup", store.UpdateGroup(alice.ID, tech, "Tech", []string{feedA, feedB}))
+		channelGroups, _ := store.ChannelGroups(alice.ID)
+		alphaID := channelByURL(t, store, alice.ID, feedA).ID
+		betaID := channelByURL(t, store, alice.ID, feedB).ID
+		if !slices.Equal(channelGroups[alphaID], []string{"Tech"}) || !slices.Equal(channelGroups[betaID], []string{"Tech"}) {
+			t.Errorf("ChannelGroups after UpdateGroup = %v", channelGroups)
+		}
+		check(t, "UpdateGroup", store.UpdateGroup(alice.ID, tech, "Tech", []string{feedB}))
+		channelGroups, _ = store.ChannelGroups(alice.ID)
+		if len(channelGroups[alphaID]) != 0 || !slices.Equal(channelGroups[betaID], []string{"Tech"}) {
+			t.Errorf("ChannelGroups after removing a channel = %v", channelGroups)
+		}
+		checkDuplicate(t, "UpdateGroup to a taken name", store.UpdateGroup(alice.ID, tech, "Music", nil))
+
+		// Another user can't rename the group or fill it with their channels.
+		checkNotFound(t, "UpdateGroup of another user's group", store.UpdateGroup(bob.ID, tech, "Mine", []string{feedB}))
+		check(t, "UpdateGroup", store.UpdateGroup(bob.ID, bobTech, "tech", []string{feedA, feedB}))
+		bobChannelGroups, _ := store.ChannelGroups(bob.ID)
+		if len(bobChannelGroups) != 1 {
+			t.Errorf("UpdateGroup added another user's channels: %v", bobChannelGroups)
+		}
+		groups, _ = store.Groups(alice.ID)
+		if groups[1].Name != "Tech" {
+			t.Errorf("group renamed by another user to %q", groups[1].Name)
+		}
+
+		checkNotFound(t, "DeleteGroup of another user's group", store.DeleteGroup(bob.ID, tech))
+		check(t, "DeleteGroup", store.DeleteGroup(alice.ID, tech))
+		if has, _ := store.HasGroup(alice.ID, tech); has {
+			t.Error("HasGroup = true after DeleteGroup")
+		}
+		if channelGroups, _ := store.ChannelGroups(alice.ID); len(channelGroups) != 0 {
+			t.Errorf("ChannelGroups after DeleteGroup = %v", channelGroups)
+		}
+		if exists, _ := store.ChannelExists(alice.ID, feedB); !exists {
+			t.Error("DeleteGroup unsubscribed from its channels")
+		}
+	})
+}
+
+func TestStoreViews(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() *sqlStore) {
+		store := newTestStore(t, open)
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+
+		check(t, "CreateView", store.CreateView(alice.ID, "Long", "duration:>20m"))
+		check(t, "CreateView", store.CreateView(alice.ID, "Fresh", "age:<1d"))
+		checkDuplicate(t, "CreateView with a taken name", store.CreateView(alice.ID, "Long", "x"))
+		check(t, "CreateView with another user's view name", store.CreateView(bob.ID, "Long", "x"))
+
+		views, err := store.Views(alice.ID)
+		check(t, "Views", err)
+		if len(views) != 2 || views[0].Name != "Long" || views[1].Name != "Fresh" {
+			t.Fatalf("Views = %+v, want them in the order they were made", views)
+		}
+		long := views[0]
+
+		view, err := store.View(alice.ID, long.ID)
+		check(t, "View", err)
+		if view != long || view.Query != "duration:>20m" {
+			t.Errorf("View = %+v, want %+v", view, long)
+		}
+		_, err = store.View(bob.ID, long.ID)
+		checkNotFound(t, "View of another user's view", err)
+
+		renamed := templates.SavedView{ID: long.ID, Name: "Longer", Query: "duration:>1h"}
+		check(t, "UpdateView", store.UpdateView(alice.ID, renamed))
+		if view, _ := store.View(alice.ID, long.ID); view != renamed {
+			t.Errorf("View after UpdateView = %+v, want %+v", view, renamed)
+		}
+		checkDuplicate(t, "UpdateView to a taken name", store.UpdateView(alice.ID, templates.SavedView{ID: long.ID, Name: "Fresh", Query: "x"}))
+		checkNotFound(t, "UpdateView of another user's view", store.UpdateView(bob.ID, templates.SavedView{ID: long.ID, Name: "Mine", Query: "x"}))
+		if view, _ := store.View(alice.ID, long.ID); view != renamed {
+			t.Errorf("another user changed a view to %+v", view)
+		}
+
+		checkNotFound(t, "DeleteView of another user's view", store.DeleteView(bob.ID, long.ID))
+		check(t, "DeleteView", store.DeleteView(alice.ID, long.ID))
+		_, err = store.View(alice.ID, long.ID)
+		checkNotFound(t, "View after DeleteView", err)
+	})
+}
+
+func TestStoreWatching(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() *sqlStore) {
+		store := newTestStore(t, open)
+		alice := createUser(t, store, "alice")
+		bob := createUser(t, store, "bob")
+
+		check(t, "SetWatched", store.SetWatched(alice.ID, "vid00000001", true))
+		check(t, "SetWatched", store.SetWatched(alice.ID, "vid00000002", true))
+		check(t, "SetWatched again", store.SetWatched(alice.ID, "vid00000001", true))
+		watched, err := store.WatchedVideoIDs(alice.ID)
+		check(t, "WatchedVideoIDs", err)
+		slices.Sort(watched)
+		if !slices.Equal(watched, []string{"vid00000001", "vid00000002"}) {
+			t.Errorf("WatchedVideoIDs = %v", watched)
+		}
+		if watched, _ := store.WatchedVideoIDs(bob.ID); len(watched) != 0 {
+			t.Errorf("another user's WatchedVideoIDs = %v", watched)
+		}
+		check(t, "SetWatched to false", store.SetWatched(alice.ID, "vid00000001", false))
+		if watched, _ := store.WatchedVideoIDs(alice.ID); !slices.Equal(watched, []string{"vid00000002"}) {
+			t.Errorf("WatchedVideoIDs after unwatching = %v", watched)
+		}
+
+		// Watch Later entries take their details from stored videos and the
+		// user's channels.
+		check(t, "AddChannel", store.AddChannel(alice.ID, templates.Channel{Name: "Alpha", URL: feedA, Alias: "A"}))
+		check(t, "StoreVideos", store.StoreVideos([]Video{{
+			VideoID: "vid00000003", ChannelID: "UCaaaaaaaaaaaaaaaaaaaaaa", Title: "Three",
+			Link: "https://www.youtube.com/watch?v=vid00000003", PublishedAt: 1_800_000_000, DurationSeconds: 90,
+		}}))
+		added := time.Unix(1_800_000_100, 0)
+		check(t, "SetWatchLater", store.SetWatchLater(alice.ID, "vid00000003", true, added))
+		check(t, "SetWatchLater", store.SetWatchLater(alice.ID, "vid00000004", true, added.Add(time.Minute)))
+		check(t, "SetWatchLater again", store.SetWatchLater(alice.ID, "vid00000003", true, added.Add(time.Hour)))
+
+		queued, err := store.InWatchLater(alice.ID, "vid00000003")
+		check(t, "InWatchLater", err)
+		if !queued {
+			t.Error("InWatchLater = false for a queued video")
+		}
+		if queued, _ := store.InWatchLater(bob.ID, "vid00000003"); queued {
+			t.Error("InWatchLater = true for another user's queue")
+		}
+
+		queue, err := store.WatchLater(alice.ID)
+		check(t, "WatchLater", err)
+		want := []WatchLaterEntry{
+			{VideoID: "vid00000003", Title: "Three", PublishedAt: 1_800_000_000, DurationSeconds: 90,
+				ChannelID: "UCaaaaaaaaaaaaaaaaaaaaaa", ChannelName: "Alpha", ChannelAlias: "A"},
+			{VideoID: "vid00000004"},
+		}
+		if !slices.Equal(queue, want) {
+			t.Errorf("WatchLater = %+v, want %+v", queue, want)
+		}
+
+		check(t, "SetWatchLater to false", store.SetWatchLater(alice.ID, "vid00000003", false, added))
+		if queued, _ := store.InWatchLater(alice.ID, "vid00000003"); queued {
+			t.Error("InWatchLater = true after removing the video")
+		}
+	})
+}
+
+func TestStoreVideos(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() *sqlStore) {
+		store := newTestStore(t, open)
+		const channelID = "UCaaaaaaaaaaaaaaaaaaaaaa"
+
+		video := func(n int, duration, views int64, wasLive bool) Video {
+			videoID := "vid0000000" + string(rune('0'+n))
+			return Video{
+				VideoID: videoID, ChannelID: channelID, Title: "Video " + videoID,
+				Link: "https://www.youtube.com/watch?v=" + videoID, PublishedAt: 1_800_000_000 + int64(n)*3600,
+				DurationSeconds: duration, Views: views, WasLive: wasLive,
+			}
+		}
+		check(t, "StoreVideos", store.StoreVideos([]Video{video(1, 60, 10, true), video(2, 0, 0, false), video(3, 120, 5, false)}))
+
+		// Unknown details don't overwrite known ones, and once live, always
+		// live.
+		updated := video(1, 0, 0, false)
+		updated.Title = "Renamed"
+		check(t, "StoreVideos again", store.StoreVideos([]Video{updated, video(2, 30, 7, false)}))
+
+		videos, err := store.Videos(channelID, 10, 0)
+		check(t, "Videos", err)
+		want := []Video{video(3, 120, 5, false), video(2, 30, 7, false), video(1, 60, 10, true)}
+		want[2].Title = "Renamed"
+		if !slices.Equal(videos, want) {
+			t.Errorf("Videos = %+v, want %+v", videos, want)
+		}
+		if page, _ := store.Videos(channelID, 1, 1); !slices.Equal(page, want[1:2]) {
+			t.Errorf("Videos with a limit and offset = %+v, want %+v", page, want[1:2])
+		}
+		if other, _ := store.Videos("UCbbbbbbbbbbbbbbbbbbbbbb", 10, 0); len(other) != 0 {
+			t.Errorf("another channel's Videos = %+v", other)
+		}
+
+		byID, err := store.VideosByID([]string{want[2].VideoID, "vid00000009", want[0].VideoID})
+		check(t, "VideosByID", err)
+		if !slices.Equal(byID, []Video{want[0], want[2]}) {
+			t.Errorf("VideosByID = %+v, want %+v", byID, []Video{want[0], want[2]})
+		}
+		if none, err := store.VideosByID(nil); err != nil || len(none) != 0 {
+			t.Errorf("VideosByID of no IDs = %+v, %v", none, err)
+		}
+
+		stats, err := store.VideoStats(channelID)
+		check(t, "VideoStats", err)
+		if stats != (VideoStats{Uploads: 3, Oldest: want[2].PublishedAt, Newest: want[0].PublishedAt}) {
+			t.Errorf("VideoStats = %+v", stats)
+		}
+		if stats, _ := store.VideoStats("UCbbbbbbbbbbbbbbbbbbbbbb"); stats != (VideoStats{}) {
+			t.Errorf("VideoStats of a channel without videos = %+v", stats)
+		}
+
+		_, err = store.FeedStatus(channelID)
+		checkNotFound(t, "FeedStatus before any fetch", err)
+
+		now := time.Unix(1_800_100_000, 0)
+		check(t, "RecordFeedFetch", store.RecordFeedFetch(channelID, errors.New("timeout"), now))
+		check(t, "RecordFeedFetch", store.RecordFeedFetch(channelID, errors.New("404"), now.Add(time.Hour)))
+		status, err := store.FeedStatus(channelID)
+		check(t, "FeedStatus", err)
+		if status != (FeedStatus{LastCheckedAt: now.Add(time.Hour).Unix(), LastError: "404", Failures: 2}) {
+			t.Errorf("FeedStatus after failures = %+v", status)
+		}
+
+		check(t, "RecordFeedFetch", store.RecordFeedFetch(channelID, nil, now.Add(2*time.Hour)))
+		status, _ = store.FeedStatus(channelID)
+		if status != (FeedStatus{LastCheckedAt: now.Add(2 * time.Hour).Unix(), LastSuccessAt: now.Add(2 * time.Hour).Unix()}) {
+			t.Errorf("FeedStatus after a success = %+v", status)
+		}
+	})
+}
+
+func TestStoreBackup(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() *sqlStore) {
+		store := open()
+		if _, err := store.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+		createUser(t, store, "alice")
+		path := filepath.Join(t.TempDir(), "backup.db")
+
+		if _, ok := store.dialect.(postgresDialect); ok {
+			if err := store.Backup(path); err == nil {
+				t.Error("Backup of a PostgreSQL database succeeded, want an error pointing at pg_dump")
+			}
+			return
+		}
+
+		check(t, "Backup", store.Backup(path))
+		if err := store.Backup(path); err == nil {
+			t.Error("Backup over an existing file succeeded")
+		}
+
+		backup, err := OpenSQLite(path)
+		check(t, "opening the backup", err)
+		defer backup.(*sqlStore).db.Close()
+		if _, _, err := backup.UserByName("alice"); err != nil {
+			t.Errorf("UserByName in the backup: %v", err)
+		}
+		if version, _ := backup.SchemaVersion(); version != latestVersion() {
+			t.Errorf("SchemaVersion of the backup = %d, want %d", version, latestVersion())
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"time"
+	"yt_rss2/templates"
+)
+
+func (s *sqlStore) CreateUser(username, passwordHash string) error {
+	_, err := s.exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", username, passwordHash)
+	return err
+}
+
+func (s *sqlStore) UserByID(userID int) (templates.User, error) {
+	var user templates.User
+	err := s.queryRow("SELECT id, username, theme FROM users WHERE id = ?", userID).Scan(&user.ID, &user.Username, &user.Theme)
+	return user, err
+}
+
+func (s *sqlStore) UserByName(username string) (templates.User, string, error) {
+	var user templates.User
+	var passwordHash string
+	err := s.queryRow("SELECT id, username, theme, password_hash FROM users WHERE username = ?", username).
+		Scan(&user.ID, &user.Username, &user.Theme, &passwordHash)
+	return user, passwordHash, err
+}
+
+func (s *sqlStore) Users() ([]UserSummary, error) {
+	rows, err := s.query(`
+		SELECT u.id, u.username, COUNT(c.id)
+		FROM users u LEFT JOIN channels c ON c.user_id = u.id
+		GROUP BY u.id ORDER BY u.username`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []UserSummary
+	for rows.Next() {
+		var user UserSummary
+		if err := rows.Scan(&user.ID, &user.Username, &user.Channels); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (s *sqlStore) SetPassword(userID int, passwordHash string) error {
+	return changedRow(s.exec("UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, userID))
+}
+
+func (s *sqlStore) DeleteUser(userID int) error {
+	return s.inTx(func(tx *sqlTx) error {
+		statements := []string{
+			"DELETE FROM channel_group_members WHERE group_id IN (SELECT id FROM channel_groups WHERE user_id = ?)",
+			"DELETE FROM channel_groups WHERE user_id = ?",
+			"DELETE FROM channels WHERE user_id = ?",
+			"DELETE FROM saved_views WHERE user_id = ?",
+			"DELETE FROM watched_videos WHERE user_id = ?",
+			"DELETE FROM watch_later WHERE user_id = ?",
+			"DELETE FROM api_tokens WHERE user_id = ?",
+			"DELETE FROM users WHERE id = ?",
+		}
+		for _, statement := range statements {
+			if _, err := tx.exec(statement, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) SetTheme(userID int, theme string) error {
+	return changedRow(s.exec("UPDATE users SET theme = ? WHERE id = ?", theme, userID))
+}
+
+func (s *sqlStore) FeedFilter(userID int) (string, error) {
+	var filter string
+	err := s.queryRow("SELECT feed_filter FROM users WHERE id = ?", userID).Scan(&filter)
+	return filter, err
+}
+
+func (s *sqlStore) SetFeedFilter(userID int, filter string) error {
+	_, err := s.exec("UPDATE users SET feed_filter = ? WHERE id = ?", filter, userID)
+	return err
+}
+
+func (s *sqlStore) SaveSettings(userID int, theme, timezone, filter string) error {
+	_, err := s.exec("UPDATE users SET theme = ?, timezone = ?, feed_filter = ? WHERE id = ?",
+		theme, timezone, filter, userID)
+	return err
+}
+
+func (s *sqlStore) VisitInfo(userID int) (string, int64, error) {
+	var timezone string
+	var previousVisit int64
+	err := s.queryRow("SELECT timezone, previous_visit_at FROM users WHERE id = ?", userID).Scan(&timezone, &previousVisit)
+	return timezone, previousVisit, err
+}
+
+func (s *sqlStore) SetTimezone(userID int, timezone string) error {
+	_, err := s.exec("UPDATE users SET timezone = ? WHERE id = ?", timezone, userID)
+	return err
+}
+
+func (s *sqlStore) RecordVisit(userID int, now time.Time, gap time.Duration) error {
+	_, err := s.exec(`
+		UPDATE users SET
+			previous_visit_at = CASE WHEN last_seen_at < ? THEN last_seen_at ELSE previous_visit_at END,
+			last_seen_at = ?
+		WHERE id = ?`, now.Add(-gap).Unix(), now.Unix(), userID)
+	return err
+}
+
+func (s *sqlStore) FeedToken(userID int) (string, error) {
+	var token string
+	err := s.queryRow("SELECT feed_token FROM users WHERE id = ?", userID).Scan(&token)
+	return token, err
+}
+
+func (s *sqlStore) SetFeedToken(userID int, token string) error {
+	return changedRow(s.exec("UPDATE users SET feed_token = ? WHERE id = ?", token, userID))
+}
+
+func (s *sqlStore) UserByFeedToken(token string) (templates.User, error) {
+	var user templates.User
+	err := s.queryRow("SELECT id, username, theme FROM users WHERE feed_token = ?", token).Scan(&user.ID, &user.Username, &user.Theme)
+	return user, err
+}
+
+func (s *sqlStore) CreateAPIToken(userID int, name, tokenHash string, created time.Time) error {
+	_, err := s.exec("INSERT INTO api_tokens (user_id, name, token_hash, created_at) VALUES (?, ?, ?, ?)",
+		userID, name, tokenHash, created.Unix())
+	return err
+}
+
+func (s *sqlStore) APITokens(userID int) ([]APIToken, error) {
+	rows, err := s.query("SELECT id, name, created_at, last_used_at FROM api_tokens WHERE user_id = ? ORDER BY created_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+	for rows.Next() {
+		var token APIToken
+		if err := rows.Scan(&token.ID, &token.Name, &token.CreatedAt, &token.LastUsedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *sqlStore) DeleteAPIToken(userID, tokenID int) error {
+	return changedRow(s.exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", tokenID, userID))
+}
+
+func (s *sqlStore) UseAPIToken(tokenHash string, now time.Time) (templates.User, error) {
+	var user templates.User
+	err := s.queryRow(`
+		SELECT u.id, u.username, u.theme FROM api_tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = ?`, tokenHash).Scan(&user.ID, &user.Username, &user.Theme)
+	if err != nil {
+		return user, err
+	}
+
+	_, err = s.exec("UPDATE api_tokens SET last_used_at = ? WHERE token_hash = ?", now.Unix(), tokenHash)
+	return user, err
+}
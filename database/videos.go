@@ -0,0 +1,195 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+func (s *sqlStore) WatchedVideoIDs(userID int) ([]string, error) {
+	rows, err := s.query("SELECT video_id FROM watched_videos WHERE user_id = ? ORDER BY watched_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var videoIDs []string
+	for rows.Next() {
+		var videoID string
+		if err := rows.Scan(&videoID); err != nil {
+			return nil, err
+		}
+		videoIDs = append(videoIDs, videoID)
+	}
+	return videoIDs, rows.Err()
+}
+
+func (s *sqlStore) SetWatched(userID int, videoID string, watched bool) error {
+	var err error
+	if watched {
+		_, err = s.exec(`
+			INSERT INTO watched_videos (user_id, video_id) VALUES (?, ?)
+			ON CONFLICT(user_id, video_id) DO UPDATE SET watched_at = CURRENT_TIMESTAMP`, userID, videoID)
+	} else {
+		_, err = s.exec("DELETE FROM watched_videos WHERE user_id = ? AND video_id = ?", userID, videoID)
+	}
+	return err
+}
+
+func (s *sqlStore) InWatchLater(userID int, videoID string) (bool, error) {
+	var count int
+	err := s.queryRow("SELECT COUNT(*) FROM watch_later WHERE user_id = ? AND video_id = ?", userID, videoID).Scan(&count)
+	return count > 0, err
+}
+
+func (s *sqlStore) SetWatchLater(userID int, videoID string, queued bool, now time.Time) error {
+	var err error
+	if queued {
+		_, err = s.exec("INSERT INTO watch_later (user_id, video_id, added_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING",
+			userID, videoID, now.Unix())
+	} else {
+		_, err = s.exec("DELETE FROM watch_later WHERE user_id = ? AND video_id = ?", userID, videoID)
+	}
+	return err
+}
+
+func (s *sqlStore) WatchLater(userID int) ([]WatchLaterEntry, error) {
+	rows, err := s.query(`
+		SELECT w.video_id, COALESCE(v.title, ''), COALESCE(v.published_at, 0), COALESCE(v.duration_seconds, 0),
+			COALESCE(v.channel_id, ''), COALESCE(c.name, ''), COALESCE(c.alias, '')
+		FROM watch_later w
+		LEFT JOIN videos v ON v.video_id = w.video_id
+		LEFT JOIN channels c ON c.user_id = w.user_id AND c.url = 'https://www.youtube.com/feeds/videos.xml?channel_id=' || v.channel_id
+		WHERE w.user_id = ?
+		ORDER BY w.added_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []WatchLaterEntry
+	for rows.Next() {
+		var entry WatchLaterEntry
+		err := rows.Scan(&entry.VideoID, &entry.Title, &entry.PublishedAt, &entry.DurationSeconds,
+			&entry.ChannelID, &entry.ChannelName, &entry.ChannelAlias)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (s *sqlStore) StoreVideos(videos []Video) error {
+	return s.inTx(func(tx *sqlTx) error {
+		stmt, err := tx.tx.Prepare(tx.dialect.rebind(`
+			INSERT INTO videos (video_id, channel_id, title, link, published_at, duration_seconds, views, was_live)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(video_id) DO UPDATE SET
+				title = excluded.title,
+				link = excluded.link,
+				duration_seconds = CASE WHEN excluded.duration_seconds > 0 THEN excluded.duration_seconds ELSE videos.duration_seconds END,
+				views = CASE WHEN excluded.views > 0 THEN excluded.views ELSE videos.views END,
+				was_live = CASE WHEN excluded.was_live = 1 THEN 1 ELSE videos.was_live END`))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, video := range videos {
+			_, err := stmt.Exec(video.VideoID, video.ChannelID, video.Title, video.Link,
+				video.PublishedAt, video.DurationSeconds, video.Views, boolInt(video.WasLive))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) Videos(channelID string, limit, offset int) ([]Video, error) {
+	rows, err := s.query(`
+		SELECT video_id, channel_id, title, link, published_at, duration_seconds, views, was_live
+		FROM videos WHERE channel_id = ?
+		ORDER BY published_at DESC
+		LIMIT ? OFFSET ?`, channelID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanVideos(rows)
+}
+
+func (s *sqlStore) VideosByID(videoIDs []string) ([]Video, error) {
+	if len(videoIDs) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(videoIDs))
+	for i, videoID := range videoIDs {
+		args[i] = videoID
+	}
+	rows, err := s.query(`
+		SELECT video_id, channel_id, title, link, published_at, duration_seconds, views, was_live
+		FROM videos WHERE video_id IN (`+strings.Repeat("?, ", len(videoIDs)-1)+`?)
+		ORDER BY published_at DESC, video_id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanVideos(rows)
+}
+
+// scanVideos reads and closes rows of the columns Videos selects.
+func scanVideos(rows *sql.Rows) ([]Video, error) {
+	defer rows.Close()
+
+	var videos []Video
+	for rows.Next() {
+		var video Video
+		err := rows.Scan(&video.VideoID, &video.ChannelID, &video.Title, &video.Link,
+			&video.PublishedAt, &video.DurationSeconds, &video.Views, &video.WasLive)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
+}
+
+func (s *sqlStore) VideoStats(channelID string) (VideoStats, error) {
+	var stats VideoStats
+	err := s.queryRow(`
+		SELECT COUNT(*), COALESCE(MIN(published_at), 0), COALESCE(MAX(published_at), 0)
+		FROM videos WHERE channel_id = ?`, channelID).Scan(&stats.Uploads, &stats.Oldest, &stats.Newest)
+	return stats, err
+}
+
+func (s *sqlStore) RecordFeedFetch(channelID string, fetchErr error, now time.Time) error {
+	var err error
+	if fetchErr == nil {
+		_, err = s.exec(`
+			INSERT INTO feed_status (channel_id, last_checked_at, last_success_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT(channel_id) DO UPDATE SET
+				last_checked_at = excluded.last_checked_at,
+				last_success_at = excluded.last_success_at,
+				last_error = '',
+				consecutive_failures = 0`, channelID, now.Unix(), now.Unix())
+	} else {
+		_, err = s.exec(`
+			INSERT INTO feed_status (channel_id, last_checked_at, last_error, consecutive_failures)
+			VALUES (?, ?, ?, 1)
+			ON CONFLICT(channel_id) DO UPDATE SET
+				last_checked_at = excluded.last_checked_at,
+				last_error = excluded.last_error,
+				consecutive_failures = feed_status.consecutive_failures + 1`, channelID, now.Unix(), fetchErr.Error())
+	}
+	return err
+}
+
+func (s *sqlStore) FeedStatus(channelID string) (FeedStatus, error) {
+	var status FeedStatus
+	err := s.queryRow(`
+		SELECT last_checked_at, last_success_at, last_error, consecutive_failures
+		FROM feed_status WHERE channel_id = ?`, channelID).
+		Scan(&status.LastCheckedAt, &status.LastSuccessAt, &status.LastError, &status.Failures)
+	return status, err
+}
@@ -0,0 +1,42 @@
+package database
+
+import "yt_rss2/templates"
+
+func (s *sqlStore) CreateView(userID int, name, query string) error {
+	_, err := s.exec("INSERT INTO saved_views (user_id, name, query) VALUES (?, ?, ?)", userID, name, query)
+	return err
+}
+
+func (s *sqlStore) View(userID, viewID int) (templates.SavedView, error) {
+	var view templates.SavedView
+	err := s.queryRow("SELECT id, name, query FROM saved_views WHERE id = ? AND user_id = ?", viewID, userID).
+		Scan(&view.ID, &view.Name, &view.Query)
+	return view, err
+}
+
+func (s *sqlStore) Views(userID int) ([]templates.SavedView, error) {
+	rows, err := s.query("SELECT id, name, query FROM saved_views WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []templates.SavedView
+	for rows.Next() {
+		var view templates.SavedView
+		if err := rows.Scan(&view.ID, &view.Name, &view.Query); err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
+func (s *sqlStore) UpdateView(userID int, view templates.SavedView) error {
+	return changedRow(s.exec("UPDATE saved_views SET name = ?, query = ? WHERE id = ? AND user_id = ?",
+		view.Name, view.Query, view.ID, userID))
+}
+
+func (s *sqlStore) DeleteView(userID, viewID int) error {
+	return changedRow(s.exec("DELETE FROM saved_views WHERE id = ? AND user_id = ?", viewID, userID))
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"slices"
+	"yt_rss2/database"
+)
+
+// These are for the command line, which manages subscriptions and feeds
+// without going through HTTP.
+
+// ImportSubscriptions adds the channels in a subscription file, in any of
+// the import formats, to a user's subscriptions. It returns how many were
+// added and how many the file had; the rest were invalid or duplicates.
+func ImportSubscriptions(userID int, data []byte) (added, total int, err error) {
+	channels, err := parseImport(data)
+	if err != nil {
+		return 0, 0, err
+	}
+	added, err = importChannels(userID, channels)
+	return added, len(channels), err
+}
+
+// ExportSubscriptions returns a user's subscriptions in an export format,
+// optionally with their per-channel settings.
+func ExportSubscriptions(userID int, format string, includeSettings bool) ([]byte, error) {
+	channels, err := getChannelsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return exportSubscriptions(channels, format, includeSettings)
+}
+
+// ExportFormats lists the export formats by name.
+func ExportFormats() []string {
+	return slices.Clone(exportFormats)
+}
+
+// RefreshFeeds fetches the feed of every channel anyone is subscribed to,
+// storing the videos and feed health as browsing the feed would. It returns
+// the number of channels and of videos fetched.
+func RefreshFeeds() (int, int, error) {
+	channels, err := database.DB.Feeds()
+	if err != nil {
+		return 0, 0, err
+	}
+	for i := range channels {
+		channels[i].ChannelID = channelIDFromFeedURL(channels[i].URL)
+	}
+
+	return len(channels), len(fetchVideos(channels)), nil
+}
@@ -0,0 +1,534 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"yt_rss2/api"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+
+	"github.com/gorilla/mux"
+	"github.com/mmcdole/gofeed"
+)
+
+// The JSON API under /api/v1 is for scripts. It authenticates with personal
+// access tokens instead of the session, and always answers in JSON, errors
+// included.
+
+const (
+	// defaultAPIPageSize and maxAPIPageSize bound the feed items per page.
+	defaultAPIPageSize = 50
+	maxAPIPageSize     = 200
+	// maxAPIBodySize limits request bodies.
+	maxAPIBodySize = 1 << 20
+)
+
+// APIAuthMiddleware authenticates API requests by the personal access token
+// in the Authorization header, and puts the user in the context like
+// AuthMiddleware does.
+func APIAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			writeAPIError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		user, err := userByAPIToken(strings.TrimSpace(token))
+		if err == database.ErrNotFound {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			writeAPIError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "user", user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// APISpecHandler serves the OpenAPI description of the app.
+func APISpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.Write(api.Spec)
+}
+
+// APINotFoundHandler keeps API errors in JSON for unknown routes.
+func APINotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusNotFound, "not found")
+}
+
+// APIChannelsHandler lists the user's channels.
+func APIChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	channels, err := getChannelsByUserID(user.ID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to load channels")
+		return
+	}
+
+	result := []api.Channel{}
+	for _, channel := range channels {
+		result = append(result, apiChannel(channel))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// APIAddChannelHandler subscribes to a channel given by handle, channel ID or
+// channel URL, as in {"channel": "@handle"}.
+func APIAddChannelHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	var body api.AddChannelRequest
+	if !readJSON(w, r, &body) {
+		return
+	}
+
+	resolved, err := resolveChannel(r.Context(), body.Channel)
+	if err == errChannelNotFound {
+		writeAPIError(w, http.StatusNotFound, "channel not found")
+		return
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, "failed to fetch channel page")
+		return
+	}
+
+	exists, err := database.DB.ChannelExists(user.ID, resolved.FeedURL)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if exists {
+		writeAPIError(w, http.StatusConflict, "channel already exists")
+		return
+	}
+
+	if err := insertChannel(user.ID, resolved); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to save channel")
+		return
+	}
+	channel, err := apiUserChannel(user.ID, channelIDFromFeedURL(resolved.FeedURL))
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to load channel")
+		return
+	}
+	writeJSON(w, http.StatusCreated, channel)
+}
+
+// APIDeleteChannelHandler unsubscribes from a channel by its channel ID.
+func APIDeleteChannelHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	channelID := mux.Vars(r)["id"]
+
+	err := database.DB.DeleteChannel(user.ID, feedURLForChannelID(channelID))
+	if err == database.ErrNotFound {
+		writeAPIError(w, http.StatusNotFound, "channel not found")
+		return
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to delete channel")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIFeedHandler returns a page of the user's feed. It takes the same
+// filters as feed URLs in the app (channel, show-shorts, sort, from, to,
+// only-new, view, group), except that channels are given by channel ID.
+// Without filters it has every channel, newest first. Pages are limit items
+// long and continue from cursor, the next_cursor of the previous page.
+func APIFeedHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	query := r.URL.Query()
+
+	limit := defaultAPIPageSize
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		var err error
+		limit, err = strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 || limit > maxAPIPageSize {
+			writeAPIError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxAPIPageSize))
+			return
+		}
+	}
+
+	values := url.Values{}
+	for key, value := range query {
+		values[key] = value
+	}
+	values.Del("channel")
+	for _, channelID := range query["channel"] {
+		if !youtubeChannelIDRegex.MatchString(channelID) {
+			writeAPIError(w, http.StatusBadRequest, "invalid channel ID: "+channelID)
+			return
+		}
+		values.Add("channel", feedURLForChannelID(channelID))
+	}
+	if sortOrder := query.Get("sort"); sortOrder != "" && !validSortOrder(sortOrder) {
+		writeAPIError(w, http.StatusBadRequest, "unknown sort order: "+sortOrder)
+		return
+	}
+	for _, key := range []string{"from", "to"} {
+		if date := query.Get(key); date != "" && validDate(date) == "" {
+			writeAPIError(w, http.StatusBadRequest, key+" must look like "+dateFormat)
+			return
+		}
+	}
+
+	// Views are checked by applyFeedScope, which gives a proper not found.
+	opts := feedOptionsFromValues(values)
+	opts.View = 0
+	opts, _, err := applyFeedScope(user.ID, opts, r)
+	if err == database.ErrNotFound {
+		writeAPIError(w, http.StatusNotFound, "group or view not found")
+		return
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	items, err := buildFeed(user.ID, opts)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to load feed")
+		return
+	}
+
+	start := 0
+	if cursor := query.Get("cursor"); cursor != "" {
+		published, videoID, ok := parseFeedCursor(cursor)
+		if !ok {
+			writeAPIError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		start = feedCursorIndex(items, published, videoID, opts.Sort)
+	}
+
+	page := api.FeedPage{Items: []api.Video{}}
+	for _, item := range items[start:min(start+limit, len(items))] {
+		page.Items = append(page.Items, apiVideo(item))
+	}
+	if start+limit < len(items) {
+		page.NextCursor = feedCursor(items[start+limit-1])
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// feedCursor returns the cursor of the page that follows item: its publish
+// time in Unix seconds and its video ID, separated by a dot.
+func feedCursor(item templates.VideoWithChannel) string {
+	return strconv.FormatInt(item.Item.PublishedParsed.Unix(), 10) + "." + item.VideoID
+}
+
+func parseFeedCursor(cursor string) (time.Time, string, bool) {
+	rawTime, videoID, ok := strings.Cut(cursor, ".")
+	if !ok || !youtubeVideoRegex.MatchString(videoID) {
+		return time.Time{}, "", false
+	}
+	unix, err := strconv.ParseInt(rawTime, 10, 64)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return time.Unix(unix, 0), videoID, true
+}
+
+// feedCursorIndex returns where the page after a cursor starts. That's
+// right after the cursor's video, or if it has left the feed since, at the
+// first video its publish time would have come before.
+func feedCursorIndex(items []templates.VideoWithChannel, published time.Time, videoID, order string) int {
+	for i, item := range items {
+		if item.VideoID == videoID {
+			return i + 1
+		}
+	}
+
+	cursor := templates.VideoWithChannel{VideoID: videoID, Item: &gofeed.Item{PublishedParsed: &published}}
+	for i, item := range items {
+		after := newerVideo(cursor, item)
+		if order == templates.SortOldest {
+			after = newerVideo(item, cursor)
+		}
+		if after {
+			return i
+		}
+	}
+	return len(items)
+}
+
+// APIWatchedHandler lists the IDs of the videos the user has watched.
+func APIWatchedHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	videoIDs, err := database.DB.WatchedVideoIDs(user.ID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if videoIDs == nil {
+		videoIDs = []string{}
+	}
+	writeJSON(w, http.StatusOK, videoIDs)
+}
+
+// APISetWatchedHandler marks a video as watched on PUT, and as not watched
+// on DELETE.
+func APISetWatchedHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	videoID := mux.Vars(r)["id"]
+	if !youtubeVideoRegex.MatchString(videoID) {
+		writeAPIError(w, http.StatusBadRequest, "invalid video ID")
+		return
+	}
+
+	if err := database.DB.SetWatched(user.ID, videoID, r.Method != http.MethodDelete); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to update watch state")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APISettingsHandler returns the user's settings.
+func APISettingsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	settings, err := loadAPISettings(user.ID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to load settings")
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// APIUpdateSettingsHandler changes the settings given in the body and
+// returns all of them.
+func APIUpdateSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	var patch api.SettingsPatch
+	if !readJSON(w, r, &patch) {
+		return
+	}
+
+	settings, err := loadAPISettings(user.ID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to load settings")
+		return
+	}
+
+	if patch.Theme != nil {
+		settings.Theme = *patch.Theme
+	}
+	if patch.Timezone != nil {
+		settings.Timezone = *patch.Timezone
+	}
+	if feed := patch.Feed; feed != nil {
+		if feed.Channels != nil {
+			settings.Feed.Channels = *feed.Channels
+		}
+		if feed.ShowShorts != nil {
+			settings.Feed.ShowShorts = *feed.ShowShorts
+		}
+		if feed.Sort != nil {
+			settings.Feed.Sort = *feed.Sort
+		}
+		if feed.From != nil {
+			settings.Feed.From = *feed.From
+		}
+		if feed.To != nil {
+			settings.Feed.To = *feed.To
+		}
+		if feed.OnlyNew != nil {
+			settings.Feed.OnlyNew = *feed.OnlyNew
+		}
+		if feed.View != nil {
+			settings.Feed.View = *feed.View
+		}
+	}
+
+	opts, problem := settingsFeedOptions(user.ID, settings.Feed)
+	if problem == "" {
+		problem = checkSettings(settings)
+	}
+	if problem != "" {
+		writeAPIError(w, http.StatusBadRequest, problem)
+		return
+	}
+
+	err = database.DB.SaveSettings(user.ID, settings.Theme, settings.Timezone, opts.values().Encode())
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to save settings")
+		return
+	}
+
+	settings, err = loadAPISettings(user.ID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to load settings")
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+func loadAPISettings(userID int) (api.Settings, error) {
+	var settings api.Settings
+	user, err := database.DB.UserByID(userID)
+	if err != nil {
+		return settings, err
+	}
+	settings.Theme = user.Theme
+	settings.Timezone, _, err = database.DB.VisitInfo(userID)
+	if err != nil {
+		return settings, err
+	}
+
+	opts := loadFeedOptions(userID)
+	settings.Feed = api.FeedSettings{
+		Channels:   []string{},
+		ShowShorts: opts.ShowShorts,
+		Sort:       opts.Sort,
+		From:       opts.From,
+		To:         opts.To,
+		OnlyNew:    opts.OnlyNew,
+		View:       opts.View,
+	}
+	for _, channelURL := range opts.values()["channel"] {
+		settings.Feed.Channels = append(settings.Feed.Channels, channelIDFromFeedURL(channelURL))
+	}
+	return settings, nil
+}
+
+// checkSettings validates the theme and timezone, returning what's wrong or
+// "".
+func checkSettings(s api.Settings) string {
+	validTheme := false
+	for _, theme := range themes {
+		validTheme = validTheme || theme == s.Theme
+	}
+	if !validTheme {
+		return "unknown theme: " + s.Theme
+	}
+	// An empty timezone is UTC, until the browser reports one.
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return "unknown timezone: " + s.Timezone
+	}
+	return ""
+}
+
+// settingsFeedOptions turns feed settings into feed options, returning
+// what's wrong with them, if anything.
+func settingsFeedOptions(userID int, feed api.FeedSettings) (feedOptions, string) {
+	opts := feedOptions{FeedForm: templates.FeedForm{
+		SelectedChannels: make(map[string]bool),
+		ShowShorts:       feed.ShowShorts,
+		Sort:             feed.Sort,
+		From:             feed.From,
+		To:               feed.To,
+		OnlyNew:          feed.OnlyNew,
+	}}
+
+	for _, channelID := range feed.Channels {
+		if !youtubeChannelIDRegex.MatchString(channelID) {
+			return opts, "invalid channel ID: " + channelID
+		}
+		opts.SelectedChannels[feedURLForChannelID(channelID)] = true
+	}
+	if !validSortOrder(feed.Sort) {
+		return opts, "unknown sort order: " + feed.Sort
+	}
+	if feed.From != "" && validDate(feed.From) == "" || feed.To != "" && validDate(feed.To) == "" {
+		return opts, "dates must look like " + dateFormat
+	}
+	if feed.View != 0 {
+		viewID, err := userViewID(userID, strconv.Itoa(feed.View))
+		if err != nil {
+			return opts, "view not found"
+		}
+		opts.View = viewID
+	}
+	return opts, ""
+}
+
+// apiUserChannel returns one of the user's channels by its channel ID.
+func apiUserChannel(userID int, channelID string) (api.Channel, error) {
+	channels, err := getChannelsByUserID(userID)
+	if err != nil {
+		return api.Channel{}, err
+	}
+	for _, channel := range channels {
+		if channel.ChannelID == channelID {
+			return apiChannel(channel), nil
+		}
+	}
+	return api.Channel{}, database.ErrNotFound
+}
+
+func apiChannel(channel templates.Channel) api.Channel {
+	groups := channel.Groups
+	if groups == nil {
+		groups = []string{}
+	}
+	return api.Channel{
+		ChannelID:    channel.ChannelID,
+		Name:         channel.Name,
+		Alias:        channel.Alias,
+		Note:         channel.Note,
+		FeedURL:      channel.URL,
+		AvatarURL:    channel.AvatarURL,
+		Groups:       groups,
+		ShortsMode:   channel.ShortsMode,
+		HideLiveVODs: channel.HideLiveVODs,
+		Priority:     channel.Priority,
+	}
+}
+
+func apiVideo(item templates.VideoWithChannel) api.Video {
+	video := api.Video{
+		VideoID:         item.VideoID,
+		Title:           item.Item.Title,
+		URL:             item.Item.Link,
+		ChannelID:       item.ChannelID,
+		ChannelName:     item.ChannelName,
+		DurationSeconds: int64(item.Duration.Seconds()),
+		Views:           item.Views,
+		IsShort:         isShort(item),
+		IsLive:          item.IsLive,
+		WasLive:         item.WasLive,
+		IsUpcoming:      item.IsUpcoming,
+		Watched:         item.Watched,
+		IsNew:           item.IsNew,
+	}
+	if item.Item.PublishedParsed != nil {
+		video.PublishedAt = item.Item.PublishedParsed.UTC()
+	}
+	if !item.ScheduledStart.IsZero() {
+		scheduled := item.ScheduledStart.UTC()
+		video.ScheduledStart = &scheduled
+	}
+	return video
+}
+
+// readJSON decodes a request body into v, answering with an error and
+// returning false if it isn't valid.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, api.Error{Error: message})
+}
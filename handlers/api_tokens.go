@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+
+	"github.com/gorilla/mux"
+)
+
+// apiTokenPrefix starts every personal access token, so they're easy to spot
+// in scripts and config files.
+const apiTokenPrefix = "ytr_"
+
+// APITokensHandler shows the popup for managing personal access tokens.
+func APITokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	renderAPITokensPopup(w, r, user.ID, "", "")
+}
+
+// AddAPITokenHandler creates a personal access token. The token itself is
+// only shown this once; just its hash is stored.
+func AddAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	r.ParseForm()
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		renderAPITokensPopup(w, r, user.ID, "", "Token name can't be empty.")
+		return
+	}
+
+	random, err := newFeedToken()
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	token := apiTokenPrefix + random
+
+	if err := database.DB.CreateAPIToken(user.ID, name, hashAPIToken(token), time.Now()); err != nil {
+		http.Error(w, "Failed to save token", http.StatusInternalServerError)
+		return
+	}
+
+	renderAPITokensPopup(w, r, user.ID, token, "")
+}
+
+// DeleteAPITokenHandler revokes a personal access token.
+func DeleteAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	tokenID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	err = database.DB.DeleteAPIToken(user.ID, tokenID)
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete token", http.StatusInternalServerError)
+		return
+	}
+	renderAPITokensPopup(w, r, user.ID, "", "")
+}
+
+func renderAPITokensPopup(w http.ResponseWriter, r *http.Request, userID int, newToken, tokenError string) {
+	tokens, err := getAPITokensByUserID(userID)
+	if err != nil {
+		http.Error(w, "Failed to load tokens", http.StatusInternalServerError)
+		return
+	}
+	templates.APITokensPopup(tokens, requestBaseURL(r), newToken, tokenError).Render(r.Context(), w)
+}
+
+func getAPITokensByUserID(userID int) ([]templates.APIToken, error) {
+	stored, err := database.DB.APITokens(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	visit := loadVisitInfo(userID)
+	var tokens []templates.APIToken
+	for _, storedToken := range stored {
+		tokens = append(tokens, templates.APIToken{
+			ID:       storedToken.ID,
+			Name:     storedToken.Name,
+			Created:  unixTime(storedToken.CreatedAt, visit.Location),
+			LastUsed: unixTime(storedToken.LastUsedAt, visit.Location),
+		})
+	}
+	return tokens, nil
+}
+
+// userByAPIToken finds the user a personal access token belongs to and notes
+// that the token was used. Unknown tokens give database.ErrNotFound.
+func userByAPIToken(token string) (templates.User, error) {
+	var user templates.User
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return user, database.ErrNotFound
+	}
+
+	return database.DB.UseAPIToken(hashAPIToken(token), time.Now())
+}
+
+// hashAPIToken hashes a token for storage. Tokens are long and random, so a
+// plain SHA-256 is enough.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"context"
-	"database/sql"
 	"log"
 	"net/http"
 	"os"
@@ -16,7 +15,9 @@ import (
 
 var store *sessions.CookieStore
 
-func init() {
+// InitSessionStore sets up the session cookies. Only the server needs it, so
+// the command line works without SESSION_KEY.
+func InitSessionStore() {
 	sessionKey := os.Getenv("SESSION_KEY")
 	if sessionKey == "" {
 		log.Fatal("SESSION_KEY environment variable not set. Please set it to a random 32-byte string.")
@@ -41,7 +42,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = database.DB.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", username, string(hashedPassword))
+	err = database.DB.CreateUser(username, string(hashedPassword))
 	if err != nil {
 		templates.Layout(templates.User{Theme: "rose-pine"}, templates.RegisterPage("Username already taken")).Render(r.Context(), w)
 		return
@@ -60,11 +61,9 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	username := r.FormValue("username")
 	password := r.FormValue("password")
 
-	var user templates.User
-	var storedHash string
-	err := database.DB.QueryRow("SELECT id, username, theme, password_hash FROM users WHERE username = ?", username).Scan(&user.ID, &user.Username, &user.Theme, &storedHash)
+	user, storedHash, err := database.DB.UserByName(username)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == database.ErrNotFound {
 			templates.Layout(templates.User{Theme: "rose-pine"}, templates.LoginPage("Invalid username or password")).Render(r.Context(), w)
 			return
 		}
@@ -110,8 +109,7 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 		
-		var user templates.User
-		err := database.DB.QueryRow("SELECT id, username, theme FROM users WHERE id = ?", userID).Scan(&user.ID, &user.Username, &user.Theme)
+		user, err := database.DB.UserByID(userID)
 		if err != nil {
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+
+	"github.com/gorilla/mux"
+)
+
+// avatarDir is where downloaded channel avatars are cached on disk.
+const avatarDir = "./avatars"
+
+// maxAvatarSize caps the size of a downloaded avatar. YouTube's are a few
+// tens of kilobytes.
+const maxAvatarSize = 2 << 20
+
+// maxChannelPageSize is how much of a channel page is read when looking for
+// its feed, name or avatar. The meta tags are near the top.
+const maxChannelPageSize = 4 << 20
+
+// maxAvatarAge is how long a cached avatar is served before it's downloaded
+// again, so a channel's new picture shows up eventually.
+const maxAvatarAge = 7 * 24 * time.Hour
+
+// avatarClient fetches avatars and the channel pages they're found on,
+// following redirects only to YouTube's own hosts.
+var avatarClient = &http.Client{
+	Timeout: 30 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if !isYouTubeImageURL(req.URL.String()) && !isYouTubePageURL(req.URL) {
+			return fmt.Errorf("refusing to follow avatar redirect to %q", req.URL)
+		}
+		return nil
+	},
+}
+
+var youtubeChannelIDRegex = regexp.MustCompile(`^UC[a-zA-Z0-9_-]{22}$`)
+
+// AvatarHandler serves a channel's avatar from the local cache, downloading it
+// first if needed. Only channels the user is subscribed to can be requested.
+func AvatarHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	channelID := mux.Vars(r)["id"]
+
+	if !youtubeChannelIDRegex.MatchString(channelID) {
+		http.Error(w, "Invalid channel ID", http.StatusBadRequest)
+		return
+	}
+
+	feedURL := feedURLForChannelID(channelID)
+	avatarURL, err := database.DB.ChannelAvatarURL(user.ID, feedURL)
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	path := filepath.Join(avatarDir, channelID+".jpg")
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > maxAvatarAge {
+		if err := fetchAvatar(feedURL, avatarURL, path); err != nil {
+			log.Printf("Error fetching avatar for %s: %v", channelID, err)
+			// An old avatar is better than none.
+			if info == nil {
+				http.Error(w, "Failed to fetch avatar", http.StatusBadGateway)
+				return
+			}
+		}
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeFile(w, r, path)
+}
+
+// fetchAvatar downloads a channel's avatar to path. The stored avatar URL is
+// tried first. When there's none, or it no longer works, the URL is looked up
+// again on the channel page and stored for every subscriber.
+func fetchAvatar(feedURL, avatarURL, path string) error {
+	var downloadErr error
+	if avatarURL != "" {
+		if downloadErr = downloadAvatar(avatarURL, path); downloadErr == nil {
+			return nil
+		}
+	}
+
+	resolvedURL, err := resolveAvatarURL(channelIDFromFeedURL(feedURL))
+	if err != nil {
+		return err
+	}
+	if resolvedURL == avatarURL {
+		return downloadErr
+	}
+	if err := database.DB.SetAvatarURL(feedURL, resolvedURL); err != nil {
+		return err
+	}
+	return downloadAvatar(resolvedURL, path)
+}
+
+// resolveAvatarURL looks up a channel's avatar on its YouTube page.
+func resolveAvatarURL(channelID string) (string, error) {
+	resp, err := avatarClient.Get("https://www.youtube.com/channel/" + channelID)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status fetching channel page: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxChannelPageSize))
+	if err != nil {
+		return "", err
+	}
+
+	return extractAvatarURL(string(body))
+}
+
+// downloadAvatar fetches the image at avatarURL and writes it to path.
+func downloadAvatar(avatarURL, path string) error {
+	// Avatar URLs can come from imports, so only fetch from YouTube's image
+	// hosts rather than anywhere the server can reach.
+	if !isYouTubeImageURL(avatarURL) {
+		return fmt.Errorf("refusing to fetch avatar from %q", avatarURL)
+	}
+
+	resp, err := avatarClient.Get(avatarURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status fetching avatar: %s", resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "image/") {
+		return fmt.Errorf("avatar is %q, not an image", contentType)
+	}
+
+	if err := os.MkdirAll(avatarDir, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a failed download never leaves a
+	// truncated image in the cache.
+	tmp, err := os.CreateTemp(avatarDir, "avatar-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// Read one byte past the limit to tell a large image from one that's
+	// exactly the limit.
+	written, err := io.Copy(tmp, io.LimitReader(resp.Body, maxAvatarSize+1))
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if written > maxAvatarSize {
+		tmp.Close()
+		return fmt.Errorf("avatar is larger than %d bytes", maxAvatarSize)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func isYouTubeImageURL(imageURL string) bool {
+	parsedURL, err := url.Parse(imageURL)
+	if err != nil || parsedURL.Scheme != "https" {
+		return false
+	}
+	host := parsedURL.Hostname()
+	return strings.HasSuffix(host, ".ggpht.com") || strings.HasSuffix(host, ".googleusercontent.com") || strings.HasSuffix(host, ".ytimg.com")
+}
+
+// isYouTubePageURL reports whether pageURL is on youtube.com, where channel
+// pages can redirect, e.g. to the consent page.
+func isYouTubePageURL(pageURL *url.URL) bool {
+	host := pageURL.Hostname()
+	return pageURL.Scheme == "https" && (host == "youtube.com" || strings.HasSuffix(host, ".youtube.com"))
+}
+
+func extractAvatarURL(htmlStr string) (string, error) {
+	re := regexp.MustCompile(`<meta property="og:image" content="([^"]+)">`)
+	matches := re.FindStringSubmatch(htmlStr)
+	if len(matches) < 2 {
+		return "", fmt.Errorf("could not find channel avatar in the page")
+	}
+	return html.UnescapeString(matches[1]), nil
+}
+
+// channelIDFromFeedURL returns the YouTube channel ID a feed URL points at.
+func channelIDFromFeedURL(feedURL string) string {
+	parsedURL, err := url.Parse(feedURL)
+	if err != nil {
+		return ""
+	}
+	return parsedURL.Query().Get("channel_id")
+}
+
+// feedURLForChannelID builds the RSS feed URL for a YouTube channel ID.
+func feedURLForChannelID(channelID string) string {
+	return "https://www.youtube.com/feeds/videos.xml?channel_id=" + channelID
+}
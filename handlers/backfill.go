@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"yt_rss2/templates"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// maxBackfillPages bounds how far back a backfill goes, in pages of 50
+// uploads, to keep within the API quota.
+const maxBackfillPages = 20
+
+// backfillCooldown is how long after a backfill finishes before the channel
+// can be backfilled again, as each run can use up to 40 API requests.
+// Failed runs can be retried sooner.
+const (
+	backfillCooldown      = 24 * time.Hour
+	backfillRetryCooldown = 10 * time.Minute
+)
+
+// backfillSlots limits how many backfills run at once.
+var backfillSlots = make(chan struct{}, 2)
+
+// youtubeAPIClient sends YouTube Data API requests.
+var youtubeAPIClient = &http.Client{Timeout: 30 * time.Second}
+
+// backfills tracks the backfill of each channel that is running or still
+// cooling down, by YouTube channel ID.
+var backfills = struct {
+	sync.Mutex
+	jobs map[string]*templates.BackfillProgress
+}{jobs: make(map[string]*templates.BackfillProgress)}
+
+// youtubeAPIBaseURL is where YouTube Data API requests go. Setting
+// YOUTUBE_API_BASE_URL points them at a stub instead.
+func youtubeAPIBaseURL() string {
+	if baseURL := os.Getenv("YOUTUBE_API_BASE_URL"); baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/")
+	}
+	return "https://www.googleapis.com/youtube/v3"
+}
+
+// backfillAvailable reports whether backfills can run at all, which needs an
+// API key.
+func backfillAvailable() bool {
+	return os.Getenv("YOUTUBE_API_KEY") != ""
+}
+
+// BackfillHandler starts a backfill of the channel on POST and reports its
+// progress on GET, which the channel page polls while it runs.
+func BackfillHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	channel, ok := userChannelFromVars(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodPost && backfillAvailable() {
+		startBackfill(channel.ChannelID, time.Now())
+	}
+	loc := loadVisitInfo(user.ID).Location
+	templates.BackfillStatus(channel.ChannelID, backfillProgress(channel.ChannelID, loc), backfillAvailable()).Render(r.Context(), w)
+}
+
+// startBackfill runs a backfill of the channel in the background, unless one
+// is already running or the last one finished too recently.
+func startBackfill(channelID string, now time.Time) {
+	if !youtubeChannelIDRegex.MatchString(channelID) {
+		return
+	}
+
+	backfills.Lock()
+	defer backfills.Unlock()
+	pruneBackfills(now)
+	if _, ok := backfills.jobs[channelID]; ok {
+		return
+	}
+
+	job := &templates.BackfillProgress{Running: true}
+	backfills.jobs[channelID] = job
+	go runBackfill(channelID, job)
+}
+
+// pruneBackfills forgets the finished backfills whose cooldown has passed.
+// backfills must be locked.
+func pruneBackfills(now time.Time) {
+	for channelID, job := range backfills.jobs {
+		if !job.Running && !now.Before(nextBackfill(job)) {
+			delete(backfills.jobs, channelID)
+		}
+	}
+}
+
+// nextBackfill is when the channel of a finished backfill can be backfilled
+// again.
+func nextBackfill(job *templates.BackfillProgress) time.Time {
+	if job.Error != "" {
+		return job.Finished.Add(backfillRetryCooldown)
+	}
+	return job.Finished.Add(backfillCooldown)
+}
+
+// backfillProgress returns a copy of the channel's backfill progress with
+// times in loc, or nil if there's none to show.
+func backfillProgress(channelID string, loc *time.Location) *templates.BackfillProgress {
+	backfills.Lock()
+	defer backfills.Unlock()
+	pruneBackfills(time.Now())
+	job, ok := backfills.jobs[channelID]
+	if !ok {
+		return nil
+	}
+	progress := *job
+	if !progress.Running {
+		progress.Finished = progress.Finished.In(loc)
+		progress.NextRun = nextBackfill(job).In(loc)
+	}
+	return &progress
+}
+
+// runBackfill pages through the channel's uploads playlist and stores every
+// video in it, so the channel's history reaches past what its RSS feed shows.
+func runBackfill(channelID string, job *templates.BackfillProgress) {
+	backfillSlots <- struct{}{}
+	defer func() { <-backfillSlots }()
+
+	err := backfillUploads(channelID, func(fetched, total int) {
+		backfills.Lock()
+		job.Fetched, job.Total = fetched, total
+		backfills.Unlock()
+	})
+	if err != nil {
+		log.Printf("Error backfilling channel %s: %v", channelID, err)
+	}
+
+	backfills.Lock()
+	defer backfills.Unlock()
+	job.Running = false
+	job.Finished = time.Now()
+	if err != nil {
+		job.Error = err.Error()
+	}
+}
+
+// playlistItemsResponse is the part of a playlistItems.list response the
+// backfill uses.
+type playlistItemsResponse struct {
+	NextPageToken string `json:"nextPageToken"`
+	PageInfo      struct {
+		TotalResults int `json:"totalResults"`
+	} `json:"pageInfo"`
+	Items []struct {
+		Snippet struct {
+			Title string `json:"title"`
+		} `json:"snippet"`
+		ContentDetails struct {
+			VideoID          string `json:"videoId"`
+			VideoPublishedAt string `json:"videoPublishedAt"`
+		} `json:"contentDetails"`
+	} `json:"items"`
+}
+
+// backfillUploads stores the channel's uploads page by page, reporting the
+// running count after each page.
+func backfillUploads(channelID string, report func(fetched, total int)) error {
+	// A channel's uploads playlist shares its ID, with UU in place of UC.
+	playlistID := "UU" + strings.TrimPrefix(channelID, "UC")
+
+	fetched := 0
+	pageToken := ""
+	for page := 0; page < maxBackfillPages; page++ {
+		resp, err := fetchPlaylistPage(playlistID, pageToken)
+		if err != nil {
+			return err
+		}
+
+		var items []templates.VideoWithChannel
+		var videoIDs []string
+		for _, entry := range resp.Items {
+			published, err := time.Parse(time.RFC3339, entry.ContentDetails.VideoPublishedAt)
+			if err != nil {
+				// Private and deleted videos have no publish date.
+				continue
+			}
+			videoID := entry.ContentDetails.VideoID
+			videoIDs = append(videoIDs, videoID)
+			items = append(items, templates.VideoWithChannel{
+				Item: &gofeed.Item{
+					Title:           entry.Snippet.Title,
+					Link:            "https://www.youtube.com/watch?v=" + videoID,
+					PublishedParsed: &published,
+				},
+				ChannelID: channelID,
+				VideoID:   videoID,
+			})
+		}
+
+		details, err := getVideoDetails(videoIDs)
+		if err != nil {
+			log.Printf("Error getting video details during backfill: %v", err)
+		}
+		for i := range items {
+			if d, ok := details[items[i].VideoID]; ok {
+				items[i].WasLive = d.WasLive
+				items[i].Duration = d.Duration
+				items[i].Views = d.Views
+			}
+		}
+
+		if err := storeVideos(items); err != nil {
+			return err
+		}
+
+		fetched += len(resp.Items)
+		report(fetched, resp.PageInfo.TotalResults)
+
+		if resp.NextPageToken == "" {
+			return nil
+		}
+		pageToken = resp.NextPageToken
+	}
+	return nil
+}
+
+func fetchPlaylistPage(playlistID, pageToken string) (*playlistItemsResponse, error) {
+	params := url.Values{}
+	params.Set("part", "snippet,contentDetails")
+	params.Set("playlistId", playlistID)
+	params.Set("maxResults", "50")
+	params.Set("key", os.Getenv("YOUTUBE_API_KEY"))
+	if pageToken != "" {
+		params.Set("pageToken", pageToken)
+	}
+
+	resp, err := youtubeAPIClient.Get(youtubeAPIBaseURL() + "/playlistItems?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("YouTube API returned %s", resp.Status)
+	}
+
+	var page playlistItemsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
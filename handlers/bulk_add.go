@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"yt_rss2/templates"
+)
+
+const (
+	// maxBulkAddLines caps how many channels can be added in one go.
+	maxBulkAddLines = 200
+	// bulkAddWorkers is how many channel pages are fetched at the same time.
+	bulkAddWorkers = 4
+	// bulkAddInterval is the minimum time between two channel page requests,
+	// so a long list doesn't get us rate limited by YouTube.
+	bulkAddInterval = 250 * time.Millisecond
+	// bulkAddTimeout caps how long resolving a whole list may take. Lines
+	// still unresolved by then are reported as failed.
+	bulkAddTimeout = 60 * time.Second
+)
+
+// BulkAddChannelHandler adds every channel in a newline-separated list of
+// handles, channel IDs or channel URLs and reports what happened to each line.
+func BulkAddChannelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		templates.BulkAddPopup().Render(r.Context(), w)
+		return
+	}
+
+	user := r.Context().Value("user").(templates.User)
+	r.ParseForm()
+
+	var inputs []string
+	for _, line := range strings.Split(r.FormValue("handles"), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			inputs = append(inputs, line)
+		}
+	}
+	if len(inputs) > maxBulkAddLines {
+		http.Error(w, fmt.Sprintf("Too many channels, please add at most %d at a time", maxBulkAddLines), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), bulkAddTimeout)
+	resolved, errs := resolveChannels(ctx, inputs)
+	cancel()
+
+	existingChannels, err := getChannelsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	existingUrls := make(map[string]bool)
+	for _, ch := range existingChannels {
+		existingUrls[ch.URL] = true
+	}
+
+	results := make([]templates.BulkAddResult, len(inputs))
+	added := 0
+	for i, input := range inputs {
+		results[i].Input = input
+		switch {
+		case errs[i] == errChannelNotFound:
+			results[i].Status = templates.BulkAddNotFound
+		case errors.Is(errs[i], context.DeadlineExceeded):
+			results[i].Status = templates.BulkAddFailed
+		case errs[i] != nil:
+			log.Printf("Bulk add: failed to resolve %q: %v", input, errs[i])
+			results[i].Status = templates.BulkAddFailed
+		case existingUrls[resolved[i].FeedURL]:
+			results[i].Name = resolved[i].Name
+			results[i].Status = templates.BulkAddDuplicate
+		default:
+			results[i].Name = resolved[i].Name
+			if err := insertChannel(user.ID, resolved[i]); err != nil {
+				log.Printf("Bulk add: failed to save %q: %v", input, err)
+				results[i].Status = templates.BulkAddFailed
+				continue
+			}
+			existingUrls[resolved[i].FeedURL] = true
+			results[i].Status = templates.BulkAddAdded
+			added++
+		}
+	}
+
+	channels, _ := getChannelsByUserID(user.ID)
+	if added > 0 {
+		w.Header().Set("HX-Trigger", "channelListChanged")
+	}
+	opts := loadFeedOptions(user.ID)
+	templates.BulkAddReport(results, channels, opts.FeedForm).Render(r.Context(), w)
+}
+
+// resolveChannels resolves each input concurrently, returning the results and
+// errors in the same order as the inputs. Inputs not resolved before ctx is
+// done get ctx's error.
+func resolveChannels(ctx context.Context, inputs []string) ([]resolvedChannel, []error) {
+	resolved := make([]resolvedChannel, len(inputs))
+	errs := make([]error, len(inputs))
+
+	limiter := time.NewTicker(bulkAddInterval)
+	defer limiter.Stop()
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range bulkAddWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				select {
+				case <-limiter.C:
+					resolved[i], errs[i] = resolveChannel(ctx, inputs[i])
+				case <-ctx.Done():
+					errs[i] = ctx.Err()
+				}
+			}
+		}()
+	}
+
+	for i := range inputs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return resolved, errs
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+)
+
+// burstWindow is the longest gap between two uploads from the same channel
+// for them to count as one burst.
+const burstWindow = 3 * time.Hour
+
+// minBurstSize is how many consecutive uploads it takes before they're
+// collapsed into one card.
+const minBurstSize = 3
+
+// maxBurstVideos caps how many videos one burst request can ask for.
+const maxBurstVideos = 50
+
+// BurstHandler renders the videos hidden behind a collapsed burst, in place
+// of its "+N more" card. The card lists their IDs, and they are loaded from
+// the stored uploads, so expanding a burst doesn't rebuild the feed.
+func BurstHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	videoIDs := r.URL.Query()["v"]
+	if len(videoIDs) == 0 || len(videoIDs) > maxBurstVideos {
+		http.Error(w, "Invalid burst", http.StatusBadRequest)
+		return
+	}
+	for _, videoID := range videoIDs {
+		if !youtubeVideoRegex.MatchString(videoID) {
+			http.Error(w, "Invalid video ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	channels, err := getChannelsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	stored, err := database.DB.VideosByID(videoIDs)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	channelsByID := make(map[string]templates.Channel)
+	for _, channel := range channels {
+		channelsByID[channel.ChannelID] = channel
+	}
+	storedByID := make(map[string]database.Video)
+	for _, video := range stored {
+		storedByID[video.VideoID] = video
+	}
+
+	// Keep the order of the feed the burst was collapsed in, and only show
+	// uploads from the user's own channels.
+	visit := loadVisitInfo(user.ID)
+	var videos []templates.VideoWithChannel
+	for _, videoID := range videoIDs {
+		video, ok := storedByID[videoID]
+		if !ok {
+			continue
+		}
+		channel, ok := channelsByID[video.ChannelID]
+		if !ok {
+			continue
+		}
+		item := storedVideoWithChannel(video, channel, visit.Location)
+		item.IsNew = item.Item.PublishedParsed.After(visit.LastVisit)
+		videos = append(videos, item)
+	}
+	if err := markWatchedVideos(user.ID, videos); err != nil {
+		log.Printf("Error loading watched videos: %v", err)
+	}
+
+	templates.Videos(videos, "").Render(r.Context(), w)
+}
+
+// collapseBursts replaces each burst in the feed with its first video,
+// noting the IDs of the rest so they can be loaded when the burst is
+// expanded.
+func collapseBursts(items []templates.VideoWithChannel) []templates.VideoWithChannel {
+	var collapsed []templates.VideoWithChannel
+	for _, burst := range splitBursts(items) {
+		leader := burst[0]
+		for _, rest := range burst[1:] {
+			leader.BurstRest = append(leader.BurstRest, rest.VideoID)
+		}
+		collapsed = append(collapsed, leader)
+	}
+	return collapsed
+}
+
+// splitBursts groups consecutive videos from the same channel that were
+// uploaded within burstWindow of each other. Runs shorter than minBurstSize
+// are left as single videos.
+func splitBursts(items []templates.VideoWithChannel) [][]templates.VideoWithChannel {
+	var bursts [][]templates.VideoWithChannel
+	for start := 0; start < len(items); {
+		end := start + 1
+		for end < len(items) && sameBurst(items[end-1], items[end]) {
+			end++
+		}
+
+		if end-start >= minBurstSize {
+			bursts = append(bursts, items[start:end])
+		} else {
+			for i := start; i < end; i++ {
+				bursts = append(bursts, items[i:i+1])
+			}
+		}
+		start = end
+	}
+	return bursts
+}
+
+func sameBurst(a, b templates.VideoWithChannel) bool {
+	if a.Channel.URL != b.Channel.URL {
+		return false
+	}
+	gap := a.Item.PublishedParsed.Sub(*b.Item.PublishedParsed)
+	return gap.Abs() <= burstWindow
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+
+	"github.com/gorilla/mux"
+)
+
+// streamDuration is how long a calendar event for a live stream or premiere
+// lasts, as YouTube doesn't say how long they'll be.
+const streamDuration = time.Hour
+
+// CalendarHandler serves an iCalendar feed of the upcoming premieres and
+// live streams from all of the user's channels. Like the output feeds, it's
+// found by the user's feed token, as calendar apps can't log in. Calendar
+// apps poll it, so events move when a stream is rescheduled.
+func CalendarHandler(w http.ResponseWriter, r *http.Request) {
+	userID, username, err := userByFeedToken(mux.Vars(r)["token"])
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	channels, err := getChannelsByUserID(userID)
+	if err != nil {
+		http.Error(w, "Failed to load channels", http.StatusInternalServerError)
+		return
+	}
+
+	var events []templates.VideoWithChannel
+	for _, item := range fetchVideos(channels) {
+		if (item.IsUpcoming || item.IsLive) && !streamStart(item).IsZero() {
+			events = append(events, item)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return streamStart(events[i]).Before(streamStart(events[j]))
+	})
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Write([]byte(calendarICS(username+"'s YouTube streams", requestBaseURL(r), events, time.Now())))
+}
+
+// streamStart is when a live stream or premiere starts, or started if it's
+// already live.
+func streamStart(item templates.VideoWithChannel) time.Time {
+	if item.IsLive && !item.LiveStart.IsZero() {
+		return item.LiveStart
+	}
+	return item.ScheduledStart
+}
+
+// calendarICS writes the events as an iCalendar document. Each video keeps
+// the same UID, so calendar apps update its event rather than adding another.
+func calendarICS(name, baseURL string, events []templates.VideoWithChannel, now time.Time) string {
+	const icsTime = "20060102T150405Z"
+	host := strings.TrimPrefix(strings.TrimPrefix(baseURL, "https://"), "http://")
+
+	var b strings.Builder
+	writeLine := func(line string) {
+		b.WriteString(foldICSLine(line))
+		b.WriteString("\r\n")
+	}
+
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//yt_rss2//Upcoming streams//EN")
+	writeLine("CALSCALE:GREGORIAN")
+	writeLine("METHOD:PUBLISH")
+	writeLine("X-WR-CALNAME:" + escapeICSText(name))
+	for _, event := range events {
+		start := streamStart(event).UTC()
+		videoURL := baseURL + "/video/" + event.VideoID
+
+		writeLine("BEGIN:VEVENT")
+		writeLine("UID:" + event.VideoID + "@" + host)
+		writeLine("DTSTAMP:" + now.UTC().Format(icsTime))
+		writeLine("DTSTART:" + start.Format(icsTime))
+		writeLine("DTEND:" + start.Add(streamDuration).Format(icsTime))
+		writeLine("SUMMARY:" + escapeICSText(event.ChannelName+": "+event.Item.Title))
+		writeLine("DESCRIPTION:" + escapeICSText(videoURL))
+		writeLine("URL:" + videoURL)
+		if event.IsLive {
+			writeLine("STATUS:CONFIRMED")
+		} else {
+			writeLine("STATUS:TENTATIVE")
+		}
+		writeLine("END:VEVENT")
+	}
+	writeLine("END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICSText escapes a value for an iCalendar TEXT property.
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine splits a content line into lines of at most 75 octets, as
+// iCalendar requires, without breaking up UTF-8 characters.
+func foldICSLine(line string) string {
+	const maxOctets = 75
+	var b strings.Builder
+	lineLength := 0
+	for _, r := range line {
+		size := len(string(r))
+		if lineLength+size > maxOctets {
+			// The continuation line's leading space counts towards its length.
+			b.WriteString("\r\n ")
+			lineLength = 1
+		}
+		b.WriteRune(r)
+		lineLength += size
+	}
+	return b.String()
+}
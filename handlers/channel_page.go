@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+
+	"github.com/gorilla/mux"
+)
+
+// channelPageSize is how many stored uploads the channel page loads at once.
+const channelPageSize = 24
+
+// ChannelPageHandler shows one of the user's channels: its details, settings,
+// feed health and the uploads stored for it.
+func ChannelPageHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	channel, ok := userChannelFromVars(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	loc := loadVisitInfo(user.ID).Location
+	stats, err := loadChannelStats(channel.ChannelID, loc)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	health, err := loadFeedHealth(channel.ChannelID, loc)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	videos, nextPage, err := channelVideosPage(user.ID, channel, 1)
+	if err != nil {
+		http.Error(w, "Failed to load videos", http.StatusInternalServerError)
+		return
+	}
+
+	templates.Layout(user, templates.ChannelPage(channel, stats, health, backfillProgress(channel.ChannelID, loc), backfillAvailable(), videos, nextPage)).Render(r.Context(), w)
+}
+
+// ChannelVideosHandler renders further pages of a channel's stored uploads.
+func ChannelVideosHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	channel, ok := userChannelFromVars(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	videos, nextPage, err := channelVideosPage(user.ID, channel, page)
+	if err != nil {
+		http.Error(w, "Failed to load videos", http.StatusInternalServerError)
+		return
+	}
+	templates.Videos(videos, nextPage).Render(r.Context(), w)
+}
+
+// userChannelFromVars looks up the channel whose YouTube ID is in the URL,
+// writing an error response if the user isn't subscribed to it.
+func userChannelFromVars(w http.ResponseWriter, r *http.Request, userID int) (templates.Channel, bool) {
+	channelID := mux.Vars(r)["id"]
+	if !youtubeChannelIDRegex.MatchString(channelID) {
+		http.Error(w, "Invalid channel ID", http.StatusBadRequest)
+		return templates.Channel{}, false
+	}
+
+	channel, err := getChannelByURL(userID, feedURLForChannelID(channelID))
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return templates.Channel{}, false
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return templates.Channel{}, false
+	}
+	return channel, true
+}
+
+// channelVideosPage loads one page of a channel's stored uploads, along with
+// the URL of the next page if there is one.
+func channelVideosPage(userID int, channel templates.Channel, page int) ([]templates.VideoWithChannel, string, error) {
+	loc := loadVisitInfo(userID).Location
+	// Ask for one more than a page to find out whether there's another.
+	videos, err := loadStoredVideos(channel, loc, channelPageSize+1, (page-1)*channelPageSize)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := markWatchedVideos(userID, videos); err != nil {
+		return nil, "", err
+	}
+
+	var nextPage string
+	if len(videos) > channelPageSize {
+		videos = videos[:channelPageSize]
+		nextPage = "/channel/" + channel.ChannelID + "/videos?page=" + strconv.Itoa(page+1)
+	}
+	return videos, nextPage, nil
+}
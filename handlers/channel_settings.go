@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+)
+
+// maxNoteLength caps the private note a user can keep on a channel.
+const maxNoteLength = 500
+
+// EditChannelHandler shows and saves the user's settings for one of their
+// channels.
+func EditChannelHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	r.ParseForm()
+	channelURL := r.URL.Query().Get("url")
+
+	channel, err := getChannelByURL(user.ID, channelURL)
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		templates.EditChannelPopup(channel).Render(r.Context(), w)
+		return
+	}
+
+	priority, _ := strconv.Atoi(r.FormValue("priority"))
+	channel.Alias = strings.TrimSpace(r.FormValue("alias"))
+	channel.Note = limitNote(r.FormValue("note"))
+	channel.ShortsMode = validShortsMode(r.FormValue("shorts-mode"))
+	channel.HideLiveVODs = r.FormValue("hide-live-vods") == "true"
+	channel.Priority = clampPriority(priority)
+	if err := database.DB.UpdateChannelSettings(user.ID, channel); err != nil {
+		http.Error(w, "Failed to save channel settings", http.StatusInternalServerError)
+		return
+	}
+
+	channels, _ := getChannelsByUserID(user.ID)
+	opts := loadFeedOptions(user.ID)
+	templates.ClosePopup("edit-channel-popup").Render(r.Context(), w)
+	templates.ChannelListOOB(channels, opts.FeedForm).Render(r.Context(), w)
+}
+
+// getChannelByURL loads one of the user's channels by its feed URL.
+func getChannelByURL(userID int, channelURL string) (templates.Channel, error) {
+	channels, err := getChannelsByUserID(userID)
+	if err != nil {
+		return templates.Channel{}, err
+	}
+	for _, channel := range channels {
+		if channel.URL == channelURL {
+			return channel, nil
+		}
+	}
+	return templates.Channel{}, database.ErrNotFound
+}
+
+// validShortsMode falls back to the default for unknown shorts modes.
+func validShortsMode(mode string) string {
+	switch mode {
+	case templates.ShortsAlways, templates.ShortsNever:
+		return mode
+	}
+	return templates.ShortsDefault
+}
+
+// clampPriority keeps a channel priority within the supported range.
+func clampPriority(priority int) int {
+	return max(0, min(priority, templates.MaxPriority))
+}
+
+// limitNote trims a note and cuts it down to maxNoteLength characters.
+func limitNote(note string) string {
+	note = strings.TrimSpace(note)
+	if runes := []rune(note); len(runes) > maxNoteLength {
+		note = string(runes[:maxNoteLength])
+	}
+	return note
+}
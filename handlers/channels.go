@@ -2,21 +2,37 @@ package handlers
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 	"yt_rss2/database"
 	"yt_rss2/templates"
 )
 
+// maxImportSize limits the size of an imported subscription list.
+const maxImportSize = 10 << 20
+
+// Channel is a subscription as it appears in exports and imports. Everything
+// but the name and URL is a per-channel setting that's only exported on
+// request.
 type Channel struct {
-	Name string `json:"name"`
-	URL  string `json:"url"`
+	Name         string   `json:"name"`
+	URL          string   `json:"url"`
+	Alias        string   `json:"alias,omitempty"`
+	Note         string   `json:"note,omitempty"`
+	ShortsMode   string   `json:"shorts_mode,omitempty"`
+	HideLiveVODs bool     `json:"hide_live_vods,omitempty"`
+	Priority     int      `json:"priority,omitempty"`
+	AvatarURL    string   `json:"avatar_url,omitempty"`
+	Groups       []string `json:"groups,omitempty"`
 }
 
 func ChannelsHandler(w http.ResponseWriter, r *http.Request) {
@@ -27,79 +43,65 @@ func ChannelsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	selectedChannels := make(map[string]bool)
-	templates.Channels(channels, selectedChannels, false, "").Render(r.Context(), w)
+	// A bookmarked or shared feed URL takes precedence over the filter the
+	// user last saved.
+	opts := loadFeedOptions(user.ID)
+	if query := r.URL.Query(); len(query) > 0 {
+		opts = feedOptionsFromValues(query)
+	}
+	templates.Channels(channels, opts.FeedForm, "").Render(r.Context(), w)
+	renderViewTabs(w, r, user.ID, opts.View)
 }
 
 func AddChannelHandler(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value("user").(templates.User)
 	r.ParseForm()
 	handle := r.FormValue("handle")
-	showShorts := r.Form.Get("show-shorts") == "true"
-
-	if !strings.HasPrefix(handle, "@") {
-		handle = "@" + handle
-	}
-
-	channelURL := "https://www.youtube.com/" + handle
+	opts := loadFeedOptions(user.ID)
 
-	resp, err := http.Get(channelURL)
-	if err != nil {
-		http.Error(w, "Failed to fetch channel page", http.StatusInternalServerError)
+	resolved, err := resolveChannel(r.Context(), handle)
+	if err == errChannelNotFound {
+		channels, _ := getChannelsByUserID(user.ID)
+		templates.Channels(channels, opts.FeedForm, "Channel not found.").Render(r.Context(), w)
 		return
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		http.Error(w, "Failed to read channel page", http.StatusInternalServerError)
+		http.Error(w, "Failed to fetch channel page", http.StatusInternalServerError)
 		return
 	}
 
-	rssURL, err := extractRSSLink(string(body))
+	exists, err := database.DB.ChannelExists(user.ID, resolved.FeedURL)
 	if err != nil {
-		http.Error(w, "Failed to find RSS link", http.StatusInternalServerError)
-		return
-	}
-
-	var existingID int
-	err = database.DB.QueryRow("SELECT id FROM channels WHERE user_id = ? AND url = ?", user.ID, rssURL).Scan(&existingID)
-	if err != sql.ErrNoRows && err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	if existingID > 0 {
+	if exists {
 		channels, _ := getChannelsByUserID(user.ID)
-		selectedChannels := make(map[string]bool)
-		templates.Channels(channels, selectedChannels, showShorts, "Channel already exists.").Render(r.Context(), w)
+		templates.Channels(channels, opts.FeedForm, "Channel already exists.").Render(r.Context(), w)
 		return
 	}
 
-	channelName, err := extractChannelName(string(body))
-	if err != nil {
-		http.Error(w, "Failed to find channel name", http.StatusInternalServerError)
-		return
-	}
-
-	_, err = database.DB.Exec("INSERT INTO channels (user_id, name, url) VALUES (?, ?, ?)", user.ID, channelName, rssURL)
-	if err != nil {
+	if err := insertChannel(user.ID, resolved); err != nil {
 		http.Error(w, "Failed to save channel", http.StatusInternalServerError)
 		return
 	}
 
 	channels, _ := getChannelsByUserID(user.ID)
 	w.Header().Set("HX-Trigger", "channelListChanged")
-	selectedChannels := make(map[string]bool)
-	templates.Channels(channels, selectedChannels, showShorts, "").Render(r.Context(), w)
+	templates.Channels(channels, opts.FeedForm, "").Render(r.Context(), w)
 }
 
 func DeleteChannelHandler(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value("user").(templates.User)
 	r.ParseForm()
 	urlToDelete := r.URL.Query().Get("url")
-	showShorts := r.Form.Get("show-shorts") == "true"
+	opts := loadFeedOptions(user.ID)
 
-	_, err := database.DB.Exec("DELETE FROM channels WHERE user_id = ? AND url = ?", user.ID, urlToDelete)
+	err := database.DB.DeleteChannel(user.ID, urlToDelete)
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to delete channel", http.StatusInternalServerError)
 		return
@@ -107,11 +109,20 @@ func DeleteChannelHandler(w http.ResponseWriter, r *http.Request) {
 
 	channels, _ := getChannelsByUserID(user.ID)
 	w.Header().Set("HX-Trigger", "channelListChanged")
-	selectedChannels := make(map[string]bool)
-	templates.Channels(channels, selectedChannels, showShorts, "").Render(r.Context(), w)
+	templates.Channels(channels, opts.FeedForm, "").Render(r.Context(), w)
 }
 
 func ExportHandler(w http.ResponseWriter, r *http.Request) {
+	var formats []templates.ExportFormat
+	for _, format := range exportFormats {
+		formats = append(formats, templates.ExportFormat{ID: format, Label: exportFiles[format].Label})
+	}
+	templates.ExportPopup(formats).Render(r.Context(), w)
+}
+
+// ExportDownloadHandler sends the user's subscriptions as a file in the
+// requested format, optionally including per-channel settings.
+func ExportDownloadHandler(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value("user").(templates.User)
 	channels, err := getChannelsByUserID(user.ID)
 	if err != nil {
@@ -119,13 +130,27 @@ func ExportHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jsonData, err := json.MarshalIndent(channels, "", "  ")
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatJSON
+	}
+	includeSettings := r.URL.Query().Get("include-settings") == "true"
+
+	data, err := exportSubscriptions(channels, format, includeSettings)
+	if err == errUnknownFormat {
+		http.Error(w, "Unknown export format", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Failed to generate JSON", http.StatusInternalServerError)
+		http.Error(w, "Failed to generate export", http.StatusInternalServerError)
 		return
 	}
 
-	templates.ExportPopup(string(jsonData)).Render(r.Context(), w)
+	file := exportFiles[format]
+	filename := fmt.Sprintf("yt-rss-%s-%s.%s", format, time.Now().Format("2006-01-02"), file.Extension)
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Write(data)
 }
 
 func ImportHandler(w http.ResponseWriter, r *http.Request) {
@@ -135,64 +160,159 @@ func ImportHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := r.Context().Value("user").(templates.User)
-	r.ParseForm()
-	jsonData := r.FormValue("json_data")
+	r.ParseMultipartForm(maxImportSize)
+
+	// An uploaded file takes precedence over pasted text.
+	importData := []byte(r.FormValue("json_data"))
+	if file, _, err := r.FormFile("import_file"); err == nil {
+		defer file.Close()
+		importData, err = io.ReadAll(io.LimitReader(file, maxImportSize))
+		if err != nil {
+			http.Error(w, "Failed to read uploaded file", http.StatusBadRequest)
+			return
+		}
+	}
 
-	var channelsToImport []Channel
-	err := json.Unmarshal([]byte(jsonData), &channelsToImport)
+	channelsToImport, err := parseImport(importData)
+	if err == errUnknownFormat {
+		http.Error(w, "Unrecognised import format", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	existingChannels, err := getChannelsByUserID(user.ID)
+	entries, err := validateImport(user.ID, channelsToImport)
 	if err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	existingUrls := make(map[string]bool)
-	for _, ch := range existingChannels {
-		existingUrls[ch.URL] = true
-	}
 
-	for _, channel := range channelsToImport {
-		if !existingUrls[channel.URL] {
-			_, err := database.DB.Exec("INSERT INTO channels (user_id, name, url) VALUES (?, ?, ?)", user.ID, channel.Name, channel.URL)
-			if err != nil {
-				http.Error(w, "Failed to import one or more channels", http.StatusInternalServerError)
-				return
-			}
-		}
+	// The preview carries the normalised import so the commit step can use
+	// every field, not just the ones shown to the user.
+	previewData, err := json.Marshal(channelsToImport)
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
 	}
 
-	w.Header().Set("HX-Trigger", "channelListChanged")
-	channels, _ := getChannelsByUserID(user.ID)
-	selectedChannels := make(map[string]bool)
-	
-	// Render the updated channels list to the main target.
-	templates.Channels(channels, selectedChannels, false, "").Render(r.Context(), w)
-	// And also render the component that closes the popup.
-	templates.ClosePopup("import-popup").Render(r.Context(), w)
+	templates.ImportPreview(entries, string(previewData)).Render(r.Context(), w)
 }
 
 func getChannelsByUserID(userID int) ([]templates.Channel, error) {
-	rows, err := database.DB.Query("SELECT name, url FROM channels WHERE user_id = ?", userID)
+	groups, err := database.DB.ChannelGroups(userID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var channels []templates.Channel
-	for rows.Next() {
-		var channel templates.Channel
-		if err := rows.Scan(&channel.Name, &channel.URL); err != nil {
-			return nil, err
-		}
-		channels = append(channels, channel)
+	channels, err := database.DB.Channels(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range channels {
+		channels[i].ChannelID = channelIDFromFeedURL(channels[i].URL)
+		channels[i].Groups = groups[channels[i].ID]
 	}
 	return channels, nil
 }
 
+// resolvedChannel is what we learn about a channel from its YouTube page.
+type resolvedChannel struct {
+	Name      string
+	FeedURL   string
+	AvatarURL string
+}
+
+var errChannelNotFound = errors.New("channel not found")
+
+// channelPageClient fetches channel pages. The timeout is per page, so one
+// slow response can't hold up an add for long.
+var channelPageClient = &http.Client{Timeout: 15 * time.Second}
+
+// resolveChannel fetches the YouTube page for a handle, channel ID or channel
+// URL and extracts the channel's RSS feed, name and avatar.
+func resolveChannel(ctx context.Context, input string) (resolvedChannel, error) {
+	pageURL, err := channelPageURL(input)
+	if err != nil {
+		return resolvedChannel{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return resolvedChannel{}, err
+	}
+	resp, err := channelPageClient.Do(req)
+	if err != nil {
+		return resolvedChannel{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return resolvedChannel{}, errChannelNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resolvedChannel{}, fmt.Errorf("unexpected status fetching channel page: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxChannelPageSize))
+	if err != nil {
+		return resolvedChannel{}, err
+	}
+
+	rssURL, err := extractRSSLink(string(body))
+	if err != nil {
+		return resolvedChannel{}, errChannelNotFound
+	}
+
+	channelName, err := extractChannelName(string(body))
+	if err != nil {
+		return resolvedChannel{}, errChannelNotFound
+	}
+
+	// A missing avatar isn't fatal; AvatarHandler will try again later.
+	avatarURL, _ := extractAvatarURL(string(body))
+
+	return resolvedChannel{Name: channelName, FeedURL: rssURL, AvatarURL: avatarURL}, nil
+}
+
+// channelPageURL turns user input into the URL of a YouTube channel page. It
+// accepts handles with or without the leading "@", bare channel IDs and
+// youtube.com channel URLs.
+func channelPageURL(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", errChannelNotFound
+	}
+
+	if youtubeChannelIDRegex.MatchString(input) {
+		return "https://www.youtube.com/channel/" + input, nil
+	}
+
+	if strings.Contains(input, "youtube.com/") {
+		if !strings.HasPrefix(input, "http://") && !strings.HasPrefix(input, "https://") {
+			input = "https://" + input
+		}
+		parsedURL, err := url.Parse(input)
+		if err != nil || (parsedURL.Host != "youtube.com" && !strings.HasSuffix(parsedURL.Host, ".youtube.com")) {
+			return "", errChannelNotFound
+		}
+		return "https://www.youtube.com" + parsedURL.EscapedPath(), nil
+	}
+
+	if strings.ContainsAny(input, "/ ") {
+		return "", errChannelNotFound
+	}
+	if !strings.HasPrefix(input, "@") {
+		input = "@" + input
+	}
+	return "https://www.youtube.com/" + input, nil
+}
+
+func insertChannel(userID int, channel resolvedChannel) error {
+	return database.DB.AddChannel(userID, templates.Channel{Name: channel.Name, URL: channel.FeedURL, AvatarURL: channel.AvatarURL})
+}
+
 func extractRSSLink(htmlStr string) (string, error) {
 	re := regexp.MustCompile(`<link rel="alternate" type="application/rss\+xml" title="RSS" href="([^"]+)">`)
 	matches := re.FindStringSubmatch(htmlStr)
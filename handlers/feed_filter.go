@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+)
+
+// feedOptionsFromValues reads feed options from the channel list form, or
+// from the query string of a bookmarked feed URL, which uses the same names.
+func feedOptionsFromValues(values url.Values) feedOptions {
+	selectedChannels := make(map[string]bool)
+	for _, channelURL := range values["channel"] {
+		selectedChannels[channelURL] = true
+	}
+
+	view, _ := strconv.Atoi(values.Get("view"))
+
+	sortOrder := values.Get("sort")
+	if !validSortOrder(sortOrder) {
+		sortOrder = templates.SortNewest
+	}
+
+	return feedOptions{
+		FeedForm: templates.FeedForm{
+			SelectedChannels: selectedChannels,
+			ShowShorts:       values.Get("show-shorts") == "true",
+			Sort:             sortOrder,
+			From:             validDate(values.Get("from")),
+			To:               validDate(values.Get("to")),
+			OnlyNew:          values.Get("only-new") == "true",
+		},
+		View: view,
+	}
+}
+
+// values turns feed options back into form values, so they can be stored and
+// put in the URL.
+func (o feedOptions) values() url.Values {
+	values := url.Values{}
+
+	var channels []string
+	for channelURL, selected := range o.SelectedChannels {
+		if selected {
+			channels = append(channels, channelURL)
+		}
+	}
+	sort.Strings(channels)
+	for _, channelURL := range channels {
+		values.Add("channel", channelURL)
+	}
+
+	if o.ShowShorts {
+		values.Set("show-shorts", "true")
+	}
+	if o.Sort != templates.SortNewest {
+		values.Set("sort", o.Sort)
+	}
+	if o.From != "" {
+		values.Set("from", o.From)
+	}
+	if o.To != "" {
+		values.Set("to", o.To)
+	}
+	if o.OnlyNew {
+		values.Set("only-new", "true")
+	}
+	if o.View > 0 {
+		values.Set("view", strconv.Itoa(o.View))
+	}
+	return values
+}
+
+// dateFormat is how the feed form's date inputs send dates.
+const dateFormat = "2006-01-02"
+
+// validDate returns date if it's a valid date input value, and "" otherwise.
+func validDate(date string) string {
+	if _, err := time.Parse(dateFormat, date); err != nil {
+		return ""
+	}
+	return date
+}
+
+// validSortOrder reports whether sortOrder is one of the offered orders.
+func validSortOrder(sortOrder string) bool {
+	for _, order := range templates.SortOrders {
+		if order.ID == sortOrder {
+			return true
+		}
+	}
+	return false
+}
+
+// loadFeedOptions returns the feed options the user last used, or the
+// defaults if they have none saved.
+func loadFeedOptions(userID int) feedOptions {
+	saved, err := database.DB.FeedFilter(userID)
+	if err != nil {
+		log.Printf("Error loading feed filter for user %d: %v", userID, err)
+	}
+
+	values, _ := url.ParseQuery(saved)
+	return feedOptionsFromValues(values)
+}
+
+// saveFeedOptions remembers the user's feed options for their next visit.
+func saveFeedOptions(userID int, opts feedOptions) error {
+	return database.DB.SetFeedFilter(userID, opts.values().Encode())
+}
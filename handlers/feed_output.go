@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"time"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+
+	"github.com/gorilla/mux"
+)
+
+// maxFeedOutputItems caps the entries in a generated feed.
+const maxFeedOutputItems = 50
+
+// FeedOutputHandler serves a user's feed as Atom, RSS or JSON Feed, for
+// reading in other apps. Feed readers can't log in, so the user is found by
+// the secret token in the URL instead of the session. The feed has all of
+// the user's channels, optionally narrowed with ?group=Name or ?view=ID.
+func FeedOutputHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	format := vars["format"]
+
+	userID, username, err := userByFeedToken(vars["token"])
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	opts, title, err := feedOutputOptions(userID, r)
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	items, err := buildFeed(userID, opts)
+	if err != nil {
+		http.Error(w, "Failed to load channels", http.StatusInternalServerError)
+		return
+	}
+	if len(items) > maxFeedOutputItems {
+		items = items[:maxFeedOutputItems]
+	}
+
+	feed := outputFeed{
+		Title:   username + "'s YouTube feed" + title,
+		HomeURL: requestBaseURL(r) + "/",
+		FeedURL: requestBaseURL(r) + r.URL.RequestURI(),
+		Items:   items,
+	}
+
+	var data []byte
+	switch format {
+	case "atom":
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		data, err = feed.atom()
+	case "rss":
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		data, err = feed.rss()
+	default:
+		w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+		data, err = feed.jsonFeed()
+	}
+	if err != nil {
+		http.Error(w, "Failed to generate feed", http.StatusInternalServerError)
+		return
+	}
+	w.Write(data)
+}
+
+// feedOutputOptions returns the options for a feed read in another app: the
+// defaults, covering every channel, narrowed only by the group or view in
+// the query string. The filter last chosen in the app is left out, so a
+// subscribed feed doesn't change as the user browses. It also returns a
+// suffix for the feed title naming the scope.
+func feedOutputOptions(userID int, r *http.Request) (feedOptions, string, error) {
+	return applyFeedScope(userID, feedOptionsFromValues(url.Values{}), r)
+}
+
+// applyFeedScope narrows feed options to the group (?group=Name) or saved
+// view (?view=ID) in the query string, returning a title suffix naming it.
+// Groups and views the user doesn't have give database.ErrNotFound.
+func applyFeedScope(userID int, opts feedOptions, r *http.Request) (feedOptions, string, error) {
+	var title string
+	if groupName := r.URL.Query().Get("group"); groupName != "" {
+		channels, err := getChannelsByUserID(userID)
+		if err != nil {
+			return opts, "", err
+		}
+		opts.SelectedChannels = make(map[string]bool)
+		for _, channel := range channels {
+			for _, group := range channel.Groups {
+				if group == groupName {
+					opts.SelectedChannels[channel.URL] = true
+				}
+			}
+		}
+		if len(opts.SelectedChannels) == 0 {
+			return opts, "", database.ErrNotFound
+		}
+		title = " (" + groupName + ")"
+	}
+
+	if rawViewID := r.URL.Query().Get("view"); rawViewID != "" {
+		viewID, err := userViewID(userID, rawViewID)
+		if err != nil {
+			return opts, "", err
+		}
+		view, err := database.DB.View(userID, viewID)
+		if err != nil {
+			return opts, "", err
+		}
+		opts.View = viewID
+		title += " (" + view.Name + ")"
+	}
+	return opts, title, nil
+}
+
+// FeedTokenHandler shows the user's feed URLs.
+func FeedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	renderFeedTokenPopup(w, r, user.ID)
+}
+
+// RegenerateFeedTokenHandler issues a new feed token, which stops the old
+// feed URLs from working.
+func RegenerateFeedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	token, err := newFeedToken()
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	if err := database.DB.SetFeedToken(user.ID, token); err != nil {
+		http.Error(w, "Failed to save token", http.StatusInternalServerError)
+		return
+	}
+	renderFeedTokenPopup(w, r, user.ID)
+}
+
+// RevokeFeedTokenHandler turns the user's feed URLs off.
+func RevokeFeedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	if err := database.DB.SetFeedToken(user.ID, ""); err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	renderFeedTokenPopup(w, r, user.ID)
+}
+
+func renderFeedTokenPopup(w http.ResponseWriter, r *http.Request, userID int) {
+	token, err := database.DB.FeedToken(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	groups, err := database.DB.Groups(userID)
+	if err != nil {
+		http.Error(w, "Failed to load groups", http.StatusInternalServerError)
+		return
+	}
+	views, err := database.DB.Views(userID)
+	if err != nil {
+		http.Error(w, "Failed to load views", http.StatusInternalServerError)
+		return
+	}
+
+	templates.FeedTokenPopup(requestBaseURL(r), token, groups, views).Render(r.Context(), w)
+}
+
+// newFeedToken returns a random token for feed URLs.
+func newFeedToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func userByFeedToken(token string) (int, string, error) {
+	if token == "" {
+		return 0, "", database.ErrNotFound
+	}
+	user, err := database.DB.UserByFeedToken(token)
+	return user.ID, user.Username, err
+}
+
+// requestBaseURL is the scheme and host the request came in on, for building
+// absolute URLs.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// outputFeed is a feed ready to be written in any of the output formats.
+type outputFeed struct {
+	Title   string
+	HomeURL string
+	FeedURL string
+	Items   []templates.VideoWithChannel
+}
+
+// updated is when the newest video in the feed was published, or now for an
+// empty feed.
+func (f outputFeed) updated() time.Time {
+	var updated time.Time
+	for _, item := range f.Items {
+		if item.Item.PublishedParsed.After(updated) {
+			updated = *item.Item.PublishedParsed
+		}
+	}
+	if updated.IsZero() {
+		return time.Now()
+	}
+	return updated
+}
+
+// videoContentHTML is the entry body: the thumbnail, linking to the video.
+func videoContentHTML(item templates.VideoWithChannel) string {
+	return fmt.Sprintf(`<a href="%s"><img src="%s" alt="%s"></a>`,
+		html.EscapeString(item.Item.Link), html.EscapeString(item.Thumbnail()), html.EscapeString(item.Item.Title))
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Author    atomAuthor  `xml:"author"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func (f outputFeed) atom() ([]byte, error) {
+	feed := atomFeed{
+		Title:   f.Title,
+		ID:      f.FeedURL,
+		Updated: f.updated().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.FeedURL, Rel: "self"},
+			{Href: f.HomeURL, Rel: "alternate"},
+		},
+	}
+	for _, item := range f.Items {
+		published := item.Item.PublishedParsed.Format(time.RFC3339)
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:     item.Item.Title,
+			ID:        "yt:video:" + item.VideoID,
+			Link:      atomLink{Href: item.Item.Link, Rel: "alternate"},
+			Published: published,
+			Updated:   published,
+			Author:    atomAuthor{Name: item.ChannelName},
+			Content:   atomContent{Type: "html", Body: videoContentHTML(item)},
+		})
+	}
+	return marshalXML(feed)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Creator     string  `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func (f outputFeed) rss() ([]byte, error) {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.HomeURL,
+			Description:   f.Title,
+			LastBuildDate: f.updated().Format(time.RFC1123Z),
+		},
+	}
+	for _, item := range f.Items {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       item.Item.Title,
+			Link:        item.Item.Link,
+			GUID:        rssGUID{Value: "yt:video:" + item.VideoID},
+			PubDate:     item.Item.PublishedParsed.Format(time.RFC1123Z),
+			Creator:     item.ChannelName,
+			Description: videoContentHTML(item),
+		})
+	}
+	return marshalXML(feed)
+}
+
+func marshalXML(v any) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	Image         string           `json:"image"`
+	DatePublished string           `json:"date_published"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+func (f outputFeed) jsonFeed() ([]byte, error) {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.HomeURL,
+		FeedURL:     f.FeedURL,
+		Items:       []jsonFeedItem{},
+	}
+	for _, item := range f.Items {
+		feed.Items = append(feed.Items, jsonFeedItem{
+			ID:            "yt:video:" + item.VideoID,
+			URL:           item.Item.Link,
+			Title:         item.Item.Title,
+			ContentHTML:   videoContentHTML(item),
+			Image:         item.Thumbnail(),
+			DatePublished: item.Item.PublishedParsed.Format(time.RFC3339),
+			Authors:       []jsonFeedAuthor{{Name: item.ChannelName}},
+		})
+	}
+	return json.MarshalIndent(feed, "", "  ")
+}
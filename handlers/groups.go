@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+
+	"github.com/gorilla/mux"
+)
+
+// GroupsHandler shows the popup for managing channel groups.
+func GroupsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	renderGroupsPopup(w, r, user.ID, "")
+}
+
+// AddGroupHandler creates a new, empty group.
+func AddGroupHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	r.ParseForm()
+	name := strings.TrimSpace(r.FormValue("name"))
+
+	if name == "" {
+		renderGroupsPopup(w, r, user.ID, "Group name can't be empty.")
+		return
+	}
+
+	err := database.DB.CreateGroup(user.ID, name)
+	if errors.Is(err, database.ErrDuplicate) {
+		renderGroupsPopup(w, r, user.ID, "A group with that name already exists.")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create group", http.StatusInternalServerError)
+		return
+	}
+
+	renderGroupsPopup(w, r, user.ID, "")
+}
+
+// UpdateGroupHandler renames a group and replaces its channels with the
+// ones ticked in the form.
+func UpdateGroupHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	groupID, err := userGroupID(user.ID, mux.Vars(r)["id"])
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	r.ParseForm()
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		renderGroupsPopup(w, r, user.ID, "Group name can't be empty.")
+		return
+	}
+
+	// Only the user's own channels can be added, whatever the form says.
+	err = database.DB.UpdateGroup(user.ID, groupID, name, r.Form["channel"])
+	if errors.Is(err, database.ErrDuplicate) {
+		renderGroupsPopup(w, r, user.ID, "A group with that name already exists.")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update group", http.StatusInternalServerError)
+		return
+	}
+
+	renderGroupsPopup(w, r, user.ID, "")
+}
+
+// DeleteGroupHandler removes a group. Its channels stay subscribed.
+func DeleteGroupHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	groupID, err := userGroupID(user.ID, mux.Vars(r)["id"])
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := database.DB.DeleteGroup(user.ID, groupID); err != nil {
+		http.Error(w, "Failed to delete group", http.StatusInternalServerError)
+		return
+	}
+
+	renderGroupsPopup(w, r, user.ID, "")
+}
+
+// renderGroupsPopup renders the group manager along with an out-of-band
+// refresh of the channel list, so the list's group sections stay current.
+func renderGroupsPopup(w http.ResponseWriter, r *http.Request, userID int, groupError string) {
+	groups, err := database.DB.Groups(userID)
+	if err != nil {
+		http.Error(w, "Failed to load groups", http.StatusInternalServerError)
+		return
+	}
+	channels, err := getChannelsByUserID(userID)
+	if err != nil {
+		http.Error(w, "Failed to load channels", http.StatusInternalServerError)
+		return
+	}
+
+	templates.GroupsPopup(groups, channels, groupError).Render(r.Context(), w)
+	if r.Method == http.MethodPost {
+		opts := loadFeedOptions(userID)
+		templates.ChannelListOOB(channels, opts.FeedForm).Render(r.Context(), w)
+	}
+}
+
+// userGroupID checks that a group ID from the URL belongs to the user.
+func userGroupID(userID int, rawID string) (int, error) {
+	groupID, err := strconv.Atoi(rawID)
+	if err != nil {
+		return 0, database.ErrNotFound
+	}
+	ok, err := database.DB.HasGroup(userID, groupID)
+	if err == nil && !ok {
+		err = database.ErrNotFound
+	}
+	return groupID, err
+}
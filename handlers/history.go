@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"log"
+	"time"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// storeVideos saves fetched videos, so a channel's history grows beyond the
+// latest 15 entries its RSS feed exposes.
+func storeVideos(items []templates.VideoWithChannel) error {
+	var videos []database.Video
+	for _, item := range items {
+		if item.ChannelID == "" || item.Item.PublishedParsed == nil {
+			continue
+		}
+		videos = append(videos, database.Video{
+			VideoID:         item.VideoID,
+			ChannelID:       item.ChannelID,
+			Title:           item.Item.Title,
+			Link:            item.Item.Link,
+			PublishedAt:     item.Item.PublishedParsed.Unix(),
+			DurationSeconds: int64(item.Duration.Seconds()),
+			Views:           item.Views,
+			WasLive:         item.WasLive,
+		})
+	}
+	return database.DB.StoreVideos(videos)
+}
+
+// recordFeedFetch keeps track of how fetching a channel's feed went, for the
+// feed health shown on the channel page.
+func recordFeedFetch(channelID string, fetchErr error, now time.Time) {
+	if channelID == "" {
+		return
+	}
+	if err := database.DB.RecordFeedFetch(channelID, fetchErr, now); err != nil {
+		log.Printf("Error recording feed status for %s: %v", channelID, err)
+	}
+}
+
+// loadFeedHealth returns the feed status of a channel, with times in loc.
+// Channels that were never fetched have a zero status.
+func loadFeedHealth(channelID string, loc *time.Location) (templates.FeedHealth, error) {
+	var health templates.FeedHealth
+	status, err := database.DB.FeedStatus(channelID)
+	if err == database.ErrNotFound {
+		return health, nil
+	}
+	if err != nil {
+		return health, err
+	}
+
+	health.LastChecked = unixTime(status.LastCheckedAt, loc)
+	health.LastSuccess = unixTime(status.LastSuccessAt, loc)
+	health.LastError = status.LastError
+	health.Failures = status.Failures
+	return health, nil
+}
+
+// loadChannelStats summarises the uploads stored for a channel.
+func loadChannelStats(channelID string, loc *time.Location) (templates.ChannelStats, error) {
+	var stats templates.ChannelStats
+	stored, err := database.DB.VideoStats(channelID)
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Uploads = stored.Uploads
+	stats.Oldest = unixTime(stored.Oldest, loc)
+	stats.Newest = unixTime(stored.Newest, loc)
+	return stats, nil
+}
+
+// loadStoredVideos returns a page of a channel's stored uploads, newest
+// first, ready for templates.Videos.
+func loadStoredVideos(channel templates.Channel, loc *time.Location, limit, offset int) ([]templates.VideoWithChannel, error) {
+	stored, err := database.DB.Videos(channel.ChannelID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var videos []templates.VideoWithChannel
+	for _, storedVideo := range stored {
+		videos = append(videos, storedVideoWithChannel(storedVideo, channel, loc))
+	}
+	return videos, nil
+}
+
+// storedVideoWithChannel turns a stored upload from channel into what
+// templates.Videos shows.
+func storedVideoWithChannel(storedVideo database.Video, channel templates.Channel, loc *time.Location) templates.VideoWithChannel {
+	var video templates.VideoWithChannel
+	video.VideoID = storedVideo.VideoID
+	video.Views = storedVideo.Views
+	video.WasLive = storedVideo.WasLive
+
+	publishedAt := time.Unix(storedVideo.PublishedAt, 0).In(loc)
+	video.Item = &gofeed.Item{Title: storedVideo.Title, Link: storedVideo.Link, PublishedParsed: &publishedAt}
+	video.Duration = time.Duration(storedVideo.DurationSeconds) * time.Second
+	video.UploadDate = publishedAt.Format("01/02/06")
+	video.ChannelName = channel.DisplayName()
+	video.ChannelOriginalName = channel.Name
+	video.ChannelID = channel.ChannelID
+	video.Channel = channel
+	return video
+}
+
+// unixTime converts a stored Unix time to loc, keeping 0 as the zero time.
+func unixTime(seconds int64, loc *time.Location) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0).In(loc)
+}
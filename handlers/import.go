@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+)
+
+// validateImport checks each channel of an import against the user's
+// existing subscriptions and the rest of the import, normalising feed URLs
+// in place along the way.
+func validateImport(userID int, channels []Channel) ([]templates.ImportEntry, error) {
+	existingChannels, err := getChannelsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	existingUrls := make(map[string]bool)
+	for _, ch := range existingChannels {
+		existingUrls[ch.URL] = true
+	}
+
+	entries := make([]templates.ImportEntry, len(channels))
+	for i, channel := range channels {
+		entry := templates.ImportEntry{
+			Name: strings.TrimSpace(channel.Name),
+			URL:  strings.TrimSpace(channel.URL),
+		}
+		channels[i].Name = entry.Name
+
+		feedURL, reason := normaliseFeedURL(entry.URL)
+		switch {
+		case reason != "":
+			entry.Status = templates.ImportRejected
+			entry.Reason = reason
+		case entry.Name == "":
+			entry.Status = templates.ImportRejected
+			entry.Reason = "Missing channel name"
+		case existingUrls[feedURL]:
+			entry.URL = feedURL
+			entry.Status = templates.ImportDuplicate
+		default:
+			entry.URL = feedURL
+			entry.Status = templates.ImportNew
+			existingUrls[feedURL] = true
+		}
+		if feedURL != "" {
+			channels[i].URL = feedURL
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+// normaliseFeedURL returns the canonical feed URL for a YouTube channel feed,
+// or a reason why the URL was rejected.
+func normaliseFeedURL(rawURL string) (string, string) {
+	if rawURL == "" {
+		return "", "Missing URL"
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return "", "Invalid URL"
+	}
+
+	if parsedURL.Host != "youtube.com" && parsedURL.Host != "www.youtube.com" {
+		return "", "Not a YouTube link"
+	}
+
+	channelID := parsedURL.Query().Get("channel_id")
+	if parsedURL.Path != "/feeds/videos.xml" || !youtubeChannelIDRegex.MatchString(channelID) {
+		return "", "Not a YouTube channel feed"
+	}
+
+	return feedURLForChannelID(channelID), ""
+}
+
+// ImportCommitHandler adds the entries the user left ticked in the import
+// preview.
+func ImportCommitHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	r.ParseForm()
+
+	var imported []Channel
+	if err := json.Unmarshal([]byte(r.FormValue("import_data")), &imported); err != nil {
+		http.Error(w, "Invalid import data", http.StatusBadRequest)
+		return
+	}
+
+	var selected []Channel
+	for _, index := range r.Form["entry"] {
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= len(imported) {
+			continue
+		}
+		selected = append(selected, imported[i])
+	}
+
+	// The preview came from the client, so validate it again in case the
+	// form was tampered with or the subscriptions changed in the meantime.
+	if _, err := importChannels(user.ID, selected); err != nil {
+		http.Error(w, "Failed to import channels", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "channelListChanged")
+	channels, _ := getChannelsByUserID(user.ID)
+	opts := loadFeedOptions(user.ID)
+
+	// Render the updated channels list to the main target.
+	templates.Channels(channels, opts.FeedForm, "").Render(r.Context(), w)
+	// And also render the component that closes the popup.
+	templates.ClosePopup("import-popup").Render(r.Context(), w)
+}
+
+// importChannels adds the channels of an import that are valid and new to
+// the user's subscriptions, returning how many it added. Everything is
+// inserted in a single transaction, so a failure leaves the subscriptions
+// untouched.
+func importChannels(userID int, channels []Channel) (int, error) {
+	entries, err := validateImport(userID, channels)
+	if err != nil {
+		return 0, err
+	}
+
+	var added []templates.Channel
+	for i, entry := range entries {
+		if entry.Status != templates.ImportNew {
+			continue
+		}
+		channel := channels[i]
+		added = append(added, templates.Channel{
+			Name:         channel.Name,
+			URL:          channel.URL,
+			AvatarURL:    channel.AvatarURL,
+			Groups:       channel.Groups,
+			Alias:        strings.TrimSpace(channel.Alias),
+			Note:         limitNote(channel.Note),
+			ShortsMode:   validShortsMode(channel.ShortsMode),
+			HideLiveVODs: channel.HideLiveVODs,
+			Priority:     clampPriority(channel.Priority),
+		})
+	}
+	if err := database.DB.ImportChannels(userID, added); err != nil {
+		return 0, err
+	}
+	return len(added), nil
+}
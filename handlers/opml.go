@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"yt_rss2/templates"
+)
+
+// opml is an OPML 2.0 document, the format feed readers use to exchange
+// subscription lists.
+type opml struct {
+	XMLName xml.Name    `xml:"opml"`
+	Version string      `xml:"version,attr"`
+	Head    opmlHead    `xml:"head"`
+	Body    []opmlEntry `xml:"body>outline"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+// opmlEntry is an outline element. Outlines with an xmlUrl are feeds; outlines
+// without one are folders containing other outlines.
+type opmlEntry struct {
+	Text     string      `xml:"text,attr"`
+	Title    string      `xml:"title,attr,omitempty"`
+	Type     string      `xml:"type,attr,omitempty"`
+	XMLURL   string      `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string      `xml:"htmlUrl,attr,omitempty"`
+	Children []opmlEntry `xml:"outline"`
+}
+
+// exportOPML renders a user's channels as an OPML 2.0 document. Grouped
+// channels go into a folder per group, so a channel in several groups appears
+// in several folders; ungrouped channels stay at the top level.
+func exportOPML(channels []templates.Channel) ([]byte, error) {
+	doc := opml{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       "YT RSS Subscriptions",
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	folders := make(map[string]*opmlEntry)
+	var folderNames []string
+	for _, channel := range channels {
+		if len(channel.Groups) == 0 {
+			doc.Body = append(doc.Body, opmlFeed(channel))
+			continue
+		}
+		for _, group := range channel.Groups {
+			folder, ok := folders[group]
+			if !ok {
+				folder = &opmlEntry{Text: group, Title: group}
+				folders[group] = folder
+				folderNames = append(folderNames, group)
+			}
+			folder.Children = append(folder.Children, opmlFeed(channel))
+		}
+	}
+	sort.Strings(folderNames)
+	for _, name := range folderNames {
+		doc.Body = append(doc.Body, *folders[name])
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func opmlFeed(channel templates.Channel) opmlEntry {
+	entry := opmlEntry{
+		Text:   channel.Name,
+		Title:  channel.Name,
+		Type:   "rss",
+		XMLURL: channel.URL,
+	}
+	if channel.ChannelID != "" {
+		entry.HTMLURL = "https://www.youtube.com/channel/" + channel.ChannelID
+	}
+	return entry
+}
+
+// importOPML extracts the YouTube channel feeds from an OPML document,
+// searching nested folders and skipping feeds from other sites. The folder a
+// feed sits in becomes its group; a feed listed in several folders is
+// returned once, in all of their groups.
+func importOPML(data []byte) ([]Channel, error) {
+	var doc opml
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var channels []Channel
+	seen := make(map[string]int)
+	var walk func(entries []opmlEntry, folder string)
+	walk = func(entries []opmlEntry, folder string) {
+		for _, entry := range entries {
+			channelID := channelIDFromFeedURL(entry.XMLURL)
+			if strings.Contains(entry.XMLURL, "youtube.com/feeds/") && youtubeChannelIDRegex.MatchString(channelID) {
+				i, ok := seen[channelID]
+				if !ok {
+					name := entry.Title
+					if name == "" {
+						name = entry.Text
+					}
+					i = len(channels)
+					seen[channelID] = i
+					channels = append(channels, Channel{Name: name, URL: feedURLForChannelID(channelID)})
+				}
+				if folder != "" && !slices.Contains(channels[i].Groups, folder) {
+					channels[i].Groups = append(channels[i].Groups, folder)
+				}
+				continue
+			}
+
+			if len(entry.Children) > 0 {
+				name := entry.Title
+				if name == "" {
+					name = entry.Text
+				}
+				walk(entry.Children, name)
+			}
+		}
+	}
+	walk(doc.Body, "")
+
+	return channels, nil
+}
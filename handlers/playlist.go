@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+)
+
+// PlaylistHandler exports videos as an extended M3U playlist for players
+// like mpv and VLC. By default it has the same feed as the output feeds, from
+// all of the user's channels; ?view=ID or ?group=Name narrow it, and
+// ?queue=watch-later gives the Watch Later queue instead. Players can't log
+// in, so the user is found by the feed token in ?token=.
+func PlaylistHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := userByFeedToken(r.URL.Query().Get("token"))
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var items []templates.VideoWithChannel
+	if r.URL.Query().Get("queue") == "watch-later" {
+		items, err = loadWatchLater(userID)
+	} else {
+		var opts feedOptions
+		opts, _, err = feedOutputOptions(userID, r)
+		if err == database.ErrNotFound {
+			http.NotFound(w, r)
+			return
+		}
+		if err == nil {
+			items, err = buildFeed(userID, opts)
+		}
+	}
+	if err != nil {
+		http.Error(w, "Failed to load videos", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+	w.Write([]byte(playlistM3U(items)))
+}
+
+// playlistM3U writes videos as an extended M3U playlist. Durations that
+// aren't known are written as -1, as the format expects.
+func playlistM3U(items []templates.VideoWithChannel) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for _, item := range items {
+		seconds := -1
+		if item.Duration > 0 {
+			seconds = int(item.Duration.Seconds())
+		}
+
+		title := item.Item.Title
+		if title == "" {
+			title = item.VideoID
+		}
+		if item.ChannelName != "" {
+			title = item.ChannelName + " - " + title
+		}
+		// Titles run to the end of the line, so they can't contain breaks.
+		title = strings.Join(strings.Fields(title), " ")
+
+		fmt.Fprintf(&b, "#EXTINF:%d,%s\n", seconds, title)
+		b.WriteString("https://www.youtube.com/watch?v=" + item.VideoID + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,464 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"yt_rss2/templates"
+)
+
+// Subscription list formats understood by import and export.
+const (
+	formatJSON     = "json"
+	formatOPML     = "opml"
+	formatCSV      = "csv"
+	formatTakeout  = "takeout"
+	formatNewPipe  = "newpipe"
+	formatFreeTube = "freetube"
+)
+
+var errUnknownFormat = errors.New("unrecognised subscription format")
+
+// exportFormats lists the export formats in the order they're offered.
+var exportFormats = []string{formatJSON, formatOPML, formatCSV, formatTakeout, formatNewPipe, formatFreeTube}
+
+// exportFile describes how an export format is offered and how a downloaded
+// export is labelled.
+type exportFile struct {
+	Label       string
+	Extension   string
+	ContentType string
+}
+
+var exportFiles = map[string]exportFile{
+	formatJSON:     {Label: "JSON (full backup)", Extension: "json", ContentType: "application/json"},
+	formatOPML:     {Label: "OPML", Extension: "opml", ContentType: "text/x-opml; charset=utf-8"},
+	formatCSV:      {Label: "CSV", Extension: "csv", ContentType: "text/csv; charset=utf-8"},
+	formatTakeout:  {Label: "Google Takeout", Extension: "csv", ContentType: "text/csv; charset=utf-8"},
+	formatNewPipe:  {Label: "NewPipe", Extension: "json", ContentType: "application/json"},
+	formatFreeTube: {Label: "FreeTube", Extension: "db", ContentType: "application/octet-stream"},
+}
+
+// csvColumn maps a column of the app's own CSV format to a channel field.
+type csvColumn struct {
+	Name string
+	Get  func(Channel) string
+	Set  func(*Channel, string)
+}
+
+// csvColumns lists the CSV columns in export order. The first two are always
+// written; the rest are per-channel settings. Imports match columns by name,
+// so only name and url are required.
+var csvColumns = []csvColumn{
+	{"name", func(c Channel) string { return c.Name }, func(c *Channel, v string) { c.Name = v }},
+	{"url", func(c Channel) string { return c.URL }, func(c *Channel, v string) { c.URL = v }},
+	{"alias", func(c Channel) string { return c.Alias }, func(c *Channel, v string) { c.Alias = v }},
+	{"note", func(c Channel) string { return c.Note }, func(c *Channel, v string) { c.Note = v }},
+	{"shorts_mode", func(c Channel) string { return c.ShortsMode }, func(c *Channel, v string) { c.ShortsMode = v }},
+	{"hide_live_vods", func(c Channel) string { return strconv.FormatBool(c.HideLiveVODs) }, func(c *Channel, v string) {
+		c.HideLiveVODs, _ = strconv.ParseBool(v)
+	}},
+	{"priority", func(c Channel) string { return strconv.Itoa(c.Priority) }, func(c *Channel, v string) {
+		c.Priority, _ = strconv.Atoi(v)
+	}},
+	{"avatar_url", func(c Channel) string { return c.AvatarURL }, func(c *Channel, v string) { c.AvatarURL = v }},
+	{"groups", func(c Channel) string { return strings.Join(c.Groups, csvGroupSeparator) }, func(c *Channel, v string) {
+		if v != "" {
+			c.Groups = strings.Split(v, csvGroupSeparator)
+		}
+	}},
+}
+
+// csvGroupSeparator separates group names within the CSV groups column.
+const csvGroupSeparator = ";"
+
+// detectImportFormat guesses the format of an uploaded or pasted
+// subscription list from its contents.
+func detectImportFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) == 0:
+		return ""
+	case trimmed[0] == '<':
+		return formatOPML
+	case trimmed[0] == '[':
+		return formatJSON
+	case trimmed[0] == '{':
+		// Our own backups and NewPipe exports are single objects, FreeTube
+		// exports a newline-delimited database of profile objects, so only
+		// the first object is probed.
+		var probe struct {
+			Channels      *json.RawMessage `json:"channels"`
+			AppVersion    *string          `json:"app_version"`
+			ID            *string          `json:"_id"`
+			Subscriptions *json.RawMessage `json:"subscriptions"`
+		}
+		if json.NewDecoder(bytes.NewReader(trimmed)).Decode(&probe) != nil {
+			return ""
+		}
+		switch {
+		case probe.Channels != nil:
+			return formatJSON
+		case probe.AppVersion != nil:
+			return formatNewPipe
+		case probe.ID != nil && probe.Subscriptions != nil:
+			return formatFreeTube
+		}
+		return ""
+	case bytes.HasPrefix(trimmed, []byte("Channel Id,")):
+		return formatTakeout
+	case bytes.HasPrefix(trimmed, []byte("name,url")):
+		return formatCSV
+	}
+	return ""
+}
+
+// parseImport converts a subscription list in any supported format into
+// channels, dropping entries that aren't YouTube channels.
+func parseImport(data []byte) ([]Channel, error) {
+	// Takeout CSVs are sometimes saved with a byte order mark.
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	switch detectImportFormat(data) {
+	case formatJSON:
+		return importJSON(data)
+	case formatOPML:
+		channels, err := importOPML(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OPML format: %w", err)
+		}
+		return channels, nil
+	case formatCSV:
+		return importCSV(data)
+	case formatTakeout:
+		return importTakeout(data)
+	case formatNewPipe:
+		return importNewPipe(data)
+	case formatFreeTube:
+		return importFreeTube(data)
+	}
+	return nil, errUnknownFormat
+}
+
+// exportSubscriptions renders channels in the given format. The JSON backup
+// always carries groups and per-channel settings, and CSV carries them on
+// request. OPML always carries groups, as folders.
+func exportSubscriptions(channels []templates.Channel, format string, includeSettings bool) ([]byte, error) {
+	switch format {
+	case formatJSON:
+		return exportJSON(channels)
+	case formatOPML:
+		return exportOPML(channels)
+	case formatCSV:
+		return exportCSV(channels, includeSettings)
+	case formatTakeout:
+		return exportTakeout(channels)
+	case formatNewPipe:
+		return exportNewPipe(channels)
+	case formatFreeTube:
+		return exportFreeTube(channels)
+	}
+	return nil, errUnknownFormat
+}
+
+// --- JSON ---
+
+// backupVersion is the version of the JSON backup format written by
+// exportJSON.
+const backupVersion = 1
+
+// backup is the app's own JSON export. Older exports were a bare array of
+// channels, which importJSON still accepts.
+type backup struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Channels   []Channel `json:"channels"`
+}
+
+func importJSON(data []byte) ([]Channel, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var channels []Channel
+		if err := json.Unmarshal(data, &channels); err != nil {
+			return nil, fmt.Errorf("invalid JSON format: %w", err)
+		}
+		return channels, nil
+	}
+
+	var b backup
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid JSON format: %w", err)
+	}
+	if b.Version > backupVersion {
+		return nil, fmt.Errorf("backup version %d is newer than this app supports", b.Version)
+	}
+	return b.Channels, nil
+}
+
+func exportJSON(channels []templates.Channel) ([]byte, error) {
+	b := backup{
+		Version:    backupVersion,
+		ExportedAt: time.Now().UTC(),
+		Channels:   []Channel{},
+	}
+	for _, channel := range channels {
+		b.Channels = append(b.Channels, exportChannel(channel, true))
+	}
+	return json.MarshalIndent(b, "", "  ")
+}
+
+// exportChannel converts a subscription to its export representation.
+func exportChannel(channel templates.Channel, includeSettings bool) Channel {
+	exported := Channel{Name: channel.Name, URL: channel.URL}
+	if includeSettings {
+		exported.Alias = channel.Alias
+		exported.Note = channel.Note
+		exported.ShortsMode = channel.ShortsMode
+		exported.HideLiveVODs = channel.HideLiveVODs
+		exported.Priority = channel.Priority
+		exported.AvatarURL = channel.AvatarURL
+		exported.Groups = channel.Groups
+	}
+	return exported
+}
+
+// --- CSV ---
+
+func importCSV(data []byte) ([]Channel, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[int]csvColumn)
+	for i, name := range records[0] {
+		for _, column := range csvColumns {
+			if column.Name == strings.TrimSpace(name) {
+				columns[i] = column
+			}
+		}
+	}
+
+	var channels []Channel
+	for _, record := range records[1:] {
+		var channel Channel
+		for i, value := range record {
+			if column, ok := columns[i]; ok {
+				column.Set(&channel, value)
+			}
+		}
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
+func exportCSV(channels []templates.Channel, includeSettings bool) ([]byte, error) {
+	columns := csvColumns[:2]
+	if includeSettings {
+		columns = csvColumns
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	var header []string
+	for _, column := range columns {
+		header = append(header, column.Name)
+	}
+	writer.Write(header)
+
+	for _, channel := range channels {
+		exported := exportChannel(channel, includeSettings)
+		var record []string
+		for _, column := range columns {
+			record = append(record, column.Get(exported))
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// --- Google Takeout ---
+
+// importTakeout reads the subscriptions.csv file from a Google Takeout
+// export, which has "Channel Id,Channel Url,Channel Title" columns.
+func importTakeout(data []byte) ([]Channel, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid Takeout CSV: %w", err)
+	}
+
+	var channels []Channel
+	for i, record := range records {
+		if i == 0 || len(record) < 3 {
+			continue
+		}
+		channelID := strings.TrimSpace(record[0])
+		if !youtubeChannelIDRegex.MatchString(channelID) {
+			continue
+		}
+		channels = append(channels, Channel{Name: record[2], URL: feedURLForChannelID(channelID)})
+	}
+	return channels, nil
+}
+
+func exportTakeout(channels []templates.Channel) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"Channel Id", "Channel Url", "Channel Title"})
+	for _, channel := range channels {
+		if channel.ChannelID == "" {
+			continue
+		}
+		writer.Write([]string{channel.ChannelID, "http://www.youtube.com/channel/" + channel.ChannelID, channel.Name})
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// --- NewPipe ---
+
+type newPipeExport struct {
+	AppVersion    string                `json:"app_version"`
+	AppVersionInt int                   `json:"app_version_int"`
+	Subscriptions []newPipeSubscription `json:"subscriptions"`
+}
+
+type newPipeSubscription struct {
+	ServiceID int    `json:"service_id"`
+	URL       string `json:"url"`
+	Name      string `json:"name"`
+}
+
+// newPipeYouTubeService is NewPipe's service ID for YouTube.
+const newPipeYouTubeService = 0
+
+func importNewPipe(data []byte) ([]Channel, error) {
+	var export newPipeExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("invalid NewPipe export: %w", err)
+	}
+
+	var channels []Channel
+	for _, sub := range export.Subscriptions {
+		if sub.ServiceID != newPipeYouTubeService {
+			continue
+		}
+		channelID := channelIDFromChannelURL(sub.URL)
+		if channelID == "" {
+			continue
+		}
+		channels = append(channels, Channel{Name: sub.Name, URL: feedURLForChannelID(channelID)})
+	}
+	return channels, nil
+}
+
+func exportNewPipe(channels []templates.Channel) ([]byte, error) {
+	export := newPipeExport{
+		AppVersion:    "0.27.6",
+		AppVersionInt: 1004,
+		Subscriptions: []newPipeSubscription{},
+	}
+	for _, channel := range channels {
+		if channel.ChannelID == "" {
+			continue
+		}
+		export.Subscriptions = append(export.Subscriptions, newPipeSubscription{
+			ServiceID: newPipeYouTubeService,
+			URL:       "https://www.youtube.com/channel/" + channel.ChannelID,
+			Name:      channel.Name,
+		})
+	}
+	return json.MarshalIndent(export, "", "  ")
+}
+
+// --- FreeTube ---
+
+// freeTubeProfile is one line of FreeTube's profiles database, which stores
+// one JSON object per line.
+type freeTubeProfile struct {
+	Name          string                 `json:"name"`
+	BgColor       string                 `json:"bgColor"`
+	TextColor     string                 `json:"textColor"`
+	Subscriptions []freeTubeSubscription `json:"subscriptions"`
+	ID            string                 `json:"_id"`
+}
+
+type freeTubeSubscription struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+// importFreeTube reads every profile in a FreeTube database export. Channels
+// that appear in several profiles are only returned once.
+func importFreeTube(data []byte) ([]Channel, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	seen := make(map[string]bool)
+
+	var channels []Channel
+	for {
+		var profile freeTubeProfile
+		err := decoder.Decode(&profile)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid FreeTube export: %w", err)
+		}
+
+		for _, sub := range profile.Subscriptions {
+			if !youtubeChannelIDRegex.MatchString(sub.ID) || seen[sub.ID] {
+				continue
+			}
+			seen[sub.ID] = true
+			channels = append(channels, Channel{Name: sub.Name, URL: feedURLForChannelID(sub.ID)})
+		}
+	}
+	return channels, nil
+}
+
+func exportFreeTube(channels []templates.Channel) ([]byte, error) {
+	profile := freeTubeProfile{
+		Name:          "All Channels",
+		BgColor:       "#000000",
+		TextColor:     "#FFFFFF",
+		Subscriptions: []freeTubeSubscription{},
+		ID:            "allChannels",
+	}
+	for _, channel := range channels {
+		if channel.ChannelID == "" {
+			continue
+		}
+		profile.Subscriptions = append(profile.Subscriptions, freeTubeSubscription{ID: channel.ChannelID, Name: channel.Name})
+	}
+
+	line, err := json.Marshal(profile)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// channelIDFromChannelURL extracts the channel ID from a
+// youtube.com/channel/<id> URL.
+func channelIDFromChannelURL(channelURL string) string {
+	_, rest, found := strings.Cut(channelURL, "youtube.com/channel/")
+	if !found {
+		return ""
+	}
+	channelID, _, _ := strings.Cut(rest, "/")
+	if !youtubeChannelIDRegex.MatchString(channelID) {
+		return ""
+	}
+	return channelID
+}
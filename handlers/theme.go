@@ -23,8 +23,7 @@ func CycleThemeHandler(w http.ResponseWriter, r *http.Request) {
 	nextTheme := themes[nextIndex]
 
 	// Update the theme in the database.
-	_, err := database.DB.Exec("UPDATE users SET theme = ? WHERE id = ?", nextTheme, user.ID)
-	if err != nil {
+	if err := database.DB.SetTheme(user.ID, nextTheme); err != nil {
 		http.Error(w, "Failed to update theme", http.StatusInternalServerError)
 		return
 	}
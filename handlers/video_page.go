@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"regexp"
 
 	"github.com/gorilla/mux"
+	"yt_rss2/database"
 	"yt_rss2/templates"
 )
 
@@ -20,5 +22,15 @@ func VideoPageHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	templates.Layout(user, templates.VideoPage(videoID)).Render(r.Context(), w)
+	// Opening a video's page is what counts as watching it.
+	if err := database.DB.SetWatched(user.ID, videoID, true); err != nil {
+		log.Printf("Error marking video %s as watched: %v", videoID, err)
+	}
+
+	queued, err := database.DB.InWatchLater(user.ID, videoID)
+	if err != nil {
+		log.Printf("Error checking Watch Later for video %s: %v", videoID, err)
+	}
+
+	templates.Layout(user, templates.VideoPage(videoID, queued)).Render(r.Context(), w)
 }
\ No newline at end of file
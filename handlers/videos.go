@@ -8,9 +8,11 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"yt_rss2/templates"
 
 	"github.com/mmcdole/gofeed"
@@ -23,112 +25,318 @@ func VideosHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Request Method: %s", r.Method)
 	log.Printf("Form values: %v", r.Form)
 
+	saveTimezone(r, user.ID)
+
 	// --- State Calculation ---
-	selectedChannels := make(map[string]bool)
-	if r.Form["channel"] != nil {
-		for _, url := range r.Form["channel"] {
-			selectedChannels[url] = true
-		}
+	opts := feedOptionsFromValues(r.Form)
+	log.Printf("Calculated showShorts boolean: %v", opts.ShowShorts)
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1 // Default to page 1
 	}
 
-	showShorts := r.Form.Get("show-shorts") == "true"
-	log.Printf("Calculated showShorts boolean: %v", showShorts)
+	// A first page means the filter changed, so remember it and reflect it in
+	// the address bar to make the view bookmarkable.
+	if page == 1 {
+		if err := saveFeedOptions(user.ID, opts); err != nil {
+			log.Printf("Error saving feed filter: %v", err)
+		}
+		w.Header().Set("HX-Replace-Url", feedPageURL(opts))
+		if err := recordVisit(user.ID, time.Now()); err != nil {
+			log.Printf("Error recording visit: %v", err)
+		}
+	}
 
-	// --- Data Fetching ---
-	channels, err := getChannelsByUserID(user.ID)
+	// --- Data Fetching, Filtering & Sorting ---
+	filteredItems, err := buildFeed(user.ID, opts)
 	if err != nil {
 		http.Error(w, "Failed to load channels", http.StatusInternalServerError)
 		return
 	}
 
-	var feedURLs []string
+	// Bursts count as a single entry, so collapse them before paginating.
+	filteredItems = collapseBursts(filteredItems)
+	markNewDivider(filteredItems, opts, loadVisitInfo(user.ID))
+
+	// --- Pagination ---
+
+	perPage := 6
+	start := (page - 1) * perPage
+	end := start + perPage
+
+	var nextPage string
+	if end < len(filteredItems) {
+		nextPage = "/videos?page=" + strconv.Itoa(page+1)
+	}
+
+	if start >= len(filteredItems) {
+		w.WriteHeader(http.StatusOK) // No more content
+		return
+	}
+
+	if end > len(filteredItems) {
+		end = len(filteredItems)
+	}
+
+	videosToShow := filteredItems[start:end]
+
+	// --- Rendering ---
+	templates.Videos(videosToShow, nextPage).Render(r.Context(), w)
+}
+
+// feedPageURL is the address of the feed page showing the given options.
+func feedPageURL(opts feedOptions) string {
+	if query := opts.values().Encode(); query != "" {
+		return "/?" + query
+	}
+	return "/"
+}
+
+// feedOptions are the user's choices about what goes into their feed.
+type feedOptions struct {
+	templates.FeedForm
+	// View is the ID of the saved view narrowing the feed, or 0 for none.
+	View int
+}
+
+// priorityWindow is how long a priority channel's uploads stay pinned to the
+// top of the feed.
+const priorityWindow = 48 * time.Hour
+
+// buildFeed fetches, filters and sorts the videos for a user's feed.
+func buildFeed(userID int, opts feedOptions) ([]templates.VideoWithChannel, error) {
+	channels, err := getChannelsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var feedChannels []templates.Channel
 	for _, channel := range channels {
-		if len(selectedChannels) == 0 || selectedChannels[channel.URL] {
-			feedURLs = append(feedURLs, channel.URL)
+		if len(opts.SelectedChannels) == 0 || opts.SelectedChannels[channel.URL] {
+			feedChannels = append(feedChannels, channel)
 		}
 	}
 
+	var view viewQuery
+	if opts.View > 0 {
+		view, err = loadViewQuery(userID, opts.View)
+		if err != nil {
+			// A deleted or broken view shouldn't take the feed down with it.
+			log.Printf("Error loading view %d: %v", opts.View, err)
+		}
+	}
+
+	allItems := fetchVideos(feedChannels)
+	if err := markWatchedVideos(userID, allItems); err != nil {
+		log.Printf("Error loading watched videos: %v", err)
+	}
+
+	visit := loadVisitInfo(userID)
+	for i := range allItems {
+		allItems[i].IsNew = allItems[i].Item.PublishedParsed.After(visit.LastVisit)
+	}
+
+	now := time.Now()
+	filteredItems := filterVideos(allItems, opts, view, feedRange(opts, visit), now)
+	filteredItems = sortVideos(filteredItems, opts.Sort, now)
+	return filteredItems, nil
+}
+
+// fetchVideos downloads the RSS feed of each channel and annotates the videos
+// with live stream information from the YouTube API. The videos are stored
+// along the way to build up each channel's upload history.
+func fetchVideos(channels []templates.Channel) []templates.VideoWithChannel {
 	fp := gofeed.NewParser()
 	var allItems []templates.VideoWithChannel
 	var videoIDs []string
-	for _, feedURL := range feedURLs {
-		feed, err := fp.ParseURL(feedURL)
+	for _, channel := range channels {
+		feed, err := fp.ParseURL(channel.URL)
+		recordFeedFetch(channel.ChannelID, err, time.Now())
 		if err == nil && feed != nil {
 			for _, item := range feed.Items {
 				videoID, err := extractVideoID(item.Link)
 				if err == nil {
 					videoIDs = append(videoIDs, videoID)
 					uploadDate := item.PublishedParsed.Format("01/02/06")
+					channelName := feed.Title
+					if channel.Alias != "" {
+						channelName = channel.Alias
+					}
 					allItems = append(allItems, templates.VideoWithChannel{
-						Item:        item,
-						ChannelName: feed.Title,
-						VideoID:     videoID,
-						UploadDate:  uploadDate,
+						Item:                item,
+						Views:               feedViewCount(item),
+						ChannelName:         channelName,
+						ChannelID:           channel.ChannelID,
+						VideoID:             videoID,
+						UploadDate:          uploadDate,
+						ChannelOriginalName: feed.Title,
+						Channel:             channel,
 					})
 				}
 			}
 		}
 	}
 
-	// --- Live Stream Detection (YouTube API) ---
-	liveStatus, err := getLiveStatus(videoIDs)
+	// --- Live Stream Detection & Durations (YouTube API) ---
+	details, err := getVideoDetails(videoIDs)
 	if err != nil {
-		log.Printf("Error getting live status: %v", err)
+		log.Printf("Error getting video details: %v", err)
 		// Don't fail the whole request, just log the error.
 	} else {
 		for i := range allItems {
-			if status, ok := liveStatus[allItems[i].VideoID]; ok && status {
-				allItems[i].IsLive = true
+			if d, ok := details[allItems[i].VideoID]; ok {
+				allItems[i].IsLive = d.IsLive
+				allItems[i].WasLive = d.WasLive
+				allItems[i].Duration = d.Duration
+				allItems[i].IsUpcoming = d.IsUpcoming
+				allItems[i].ScheduledStart = d.ScheduledStart
+				allItems[i].LiveStart = d.LiveStart
+				if d.Views > 0 {
+					allItems[i].Views = d.Views
+				}
 			}
 		}
 	}
 
-	// --- Filtering & Sorting ---
+	if err := storeVideos(allItems); err != nil {
+		log.Printf("Error storing videos: %v", err)
+	}
+
+	return allItems
+}
+
+// filterVideos drops the videos the user doesn't want to see, applying each
+// channel's own preferences over the global ones, then the saved view.
+func filterVideos(items []templates.VideoWithChannel, opts feedOptions, view viewQuery, published publishedRange, now time.Time) []templates.VideoWithChannel {
 	var filteredItems []templates.VideoWithChannel
-	if !showShorts {
-		log.Println("Filtering shorts...")
-		for _, item := range allItems {
-			if !strings.Contains(item.Item.Link, "/shorts/") {
-				filteredItems = append(filteredItems, item)
+	for _, item := range items {
+		if !published.contains(*item.Item.PublishedParsed) {
+			continue
+		}
+
+		showShorts := opts.ShowShorts || view.decidesShorts()
+		switch item.Channel.ShortsMode {
+		case templates.ShortsAlways:
+			showShorts = true
+		case templates.ShortsNever:
+			showShorts = false
+		}
+		if !showShorts && isShort(item) {
+			continue
+		}
+
+		if item.Channel.HideLiveVODs && item.WasLive && !item.IsLive {
+			continue
+		}
+
+		if !view.matches(item, now) {
+			continue
+		}
+
+		filteredItems = append(filteredItems, item)
+	}
+	return filteredItems
+}
+
+// sortVideos orders the feed by the given sort order, except that recent
+// uploads from priority channels are pinned above everything else, highest
+// priority first.
+func sortVideos(items []templates.VideoWithChannel, order string, now time.Time) []templates.VideoWithChannel {
+	pinned := func(item templates.VideoWithChannel) int {
+		if item.Channel.Priority > 0 && now.Sub(*item.Item.PublishedParsed) < priorityWindow {
+			return item.Channel.Priority
+		}
+		return 0
+	}
+	less := newerVideo
+	switch order {
+	case templates.SortOldest:
+		less = func(a, b templates.VideoWithChannel) bool { return newerVideo(b, a) }
+	case templates.SortChannel:
+		less = func(a, b templates.VideoWithChannel) bool {
+			nameA, nameB := strings.ToLower(a.ChannelName), strings.ToLower(b.ChannelName)
+			if nameA != nameB {
+				return nameA < nameB
 			}
+			return newerVideo(a, b)
 		}
-	} else {
-		log.Println("Not filtering shorts.")
-		filteredItems = allItems
+	case templates.SortViews:
+		less = func(a, b templates.VideoWithChannel) bool { return a.Views > b.Views }
+	case templates.SortDuration:
+		less = func(a, b templates.VideoWithChannel) bool { return a.Duration > b.Duration }
 	}
 
-	sort.Slice(filteredItems, func(i, j int) bool {
-		return filteredItems[i].Item.PublishedParsed.After(*filteredItems[j].Item.PublishedParsed)
+	sort.SliceStable(items, func(i, j int) bool {
+		if pi, pj := pinned(items[i]), pinned(items[j]); pi != pj {
+			return pi > pj
+		}
+		return less(items[i], items[j])
 	})
 
-	// --- Pagination ---
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page == 0 {
-		page = 1 // Default to page 1
+	if order == templates.SortRoundRobin {
+		unpinned := 0
+		for unpinned < len(items) && pinned(items[unpinned]) > 0 {
+			unpinned++
+		}
+		items = append(items[:unpinned], interleaveChannels(items[unpinned:])...)
 	}
+	return items
+}
 
-	perPage := 6
-	start := (page - 1) * perPage
-	end := start + perPage
-
-	var nextPage int
-	if end < len(filteredItems) {
-		nextPage = page + 1
+// newerVideo reports whether a was published after b. Videos published at
+// the same time are ordered by ID, so that pages of the feed are stable.
+func newerVideo(a, b templates.VideoWithChannel) bool {
+	if !a.Item.PublishedParsed.Equal(*b.Item.PublishedParsed) {
+		return a.Item.PublishedParsed.After(*b.Item.PublishedParsed)
 	}
+	return a.VideoID < b.VideoID
+}
 
-	if start >= len(filteredItems) {
-		w.WriteHeader(http.StatusOK) // No more content
-		return
+// interleaveChannels takes one video from each channel in turn, so a channel
+// that uploads a batch at once doesn't bury everyone else. Videos must
+// already be newest first; channels take turns in order of their newest
+// upload.
+func interleaveChannels(items []templates.VideoWithChannel) []templates.VideoWithChannel {
+	var channelOrder []string
+	byChannel := make(map[string][]templates.VideoWithChannel)
+	for _, item := range items {
+		if _, ok := byChannel[item.Channel.URL]; !ok {
+			channelOrder = append(channelOrder, item.Channel.URL)
+		}
+		byChannel[item.Channel.URL] = append(byChannel[item.Channel.URL], item)
 	}
 
-	if end > len(filteredItems) {
-		end = len(filteredItems)
+	interleaved := make([]templates.VideoWithChannel, 0, len(items))
+	for len(interleaved) < len(items) {
+		for _, channelURL := range channelOrder {
+			if queue := byChannel[channelURL]; len(queue) > 0 {
+				interleaved = append(interleaved, queue[0])
+				byChannel[channelURL] = queue[1:]
+			}
+		}
 	}
+	return interleaved
+}
 
-	videosToShow := filteredItems[start:end]
+// feedViewCount reads the view count YouTube puts in its RSS feeds, which
+// serves when the API isn't available.
+func feedViewCount(item *gofeed.Item) int64 {
+	for _, group := range item.Extensions["media"]["group"] {
+		for _, community := range group.Children["community"] {
+			for _, statistics := range community.Children["statistics"] {
+				views, err := strconv.ParseInt(statistics.Attrs["views"], 10, 64)
+				if err == nil {
+					return views
+				}
+			}
+		}
+	}
+	return 0
+}
 
-	// --- Rendering ---
-	templates.Videos(videosToShow, nextPage).Render(r.Context(), w)
+func isShort(item templates.VideoWithChannel) bool {
+	return strings.Contains(item.Item.Link, "/shorts/")
 }
 
 // extractVideoID parses a YouTube URL and returns the video ID.
@@ -162,21 +370,48 @@ type YouTubeResponse struct {
 		Snippet struct {
 			LiveBroadcastContent string `json:"liveBroadcastContent"`
 		} `json:"snippet"`
+		ContentDetails struct {
+			Duration string `json:"duration"`
+		} `json:"contentDetails"`
+		Statistics struct {
+			ViewCount string `json:"viewCount"`
+		} `json:"statistics"`
+		// LiveStreamingDetails is only present for videos that are, were or
+		// will be live streams, which includes premieres.
+		LiveStreamingDetails *struct {
+			ScheduledStartTime string `json:"scheduledStartTime"`
+			ActualStartTime    string `json:"actualStartTime"`
+			ActualEndTime      string `json:"actualEndTime"`
+		} `json:"liveStreamingDetails"`
 	} `json:"items"`
 }
 
-func getLiveStatus(videoIDs []string) (map[string]bool, error) {
+// videoDetails is what the YouTube API adds to a feed entry: whether a video
+// is live right now, whether it is or was a live stream at all (which
+// includes the recordings of past streams), how long it is and how often it
+// was viewed. Upcoming streams and premieres also have their scheduled start.
+type videoDetails struct {
+	IsLive         bool
+	WasLive        bool
+	Duration       time.Duration
+	Views          int64
+	IsUpcoming     bool
+	ScheduledStart time.Time
+	LiveStart      time.Time
+}
+
+func getVideoDetails(videoIDs []string) (map[string]videoDetails, error) {
 	apiKey := os.Getenv("YOUTUBE_API_KEY")
 	if apiKey == "" {
 		return nil, fmt.Errorf("YOUTUBE_API_KEY not set")
 	}
 
 	if len(videoIDs) == 0 {
-		return make(map[string]bool), nil
+		return make(map[string]videoDetails), nil
 	}
 
-	liveStatus := make(map[string]bool)
-	
+	details := make(map[string]videoDetails)
+
 	// Chunk the video IDs into groups of 50.
 	chunkSize := 50
 	for i := 0; i < len(videoIDs); i += chunkSize {
@@ -187,10 +422,10 @@ func getLiveStatus(videoIDs []string) (map[string]bool, error) {
 		chunk := videoIDs[i:end]
 
 		ids := strings.Join(chunk, ",")
-		apiURL := fmt.Sprintf("https://www.googleapis.com/youtube/v3/videos?part=snippet&id=%s&key=%s", ids, apiKey)
+		apiURL := fmt.Sprintf("%s/videos?part=snippet,contentDetails,statistics,liveStreamingDetails&id=%s&key=%s", youtubeAPIBaseURL(), ids, apiKey)
 		log.Printf("Calling YouTube API: %s", apiURL)
 
-		resp, err := http.Get(apiURL)
+		resp, err := youtubeAPIClient.Get(apiURL)
 		if err != nil {
 			return nil, err
 		}
@@ -208,11 +443,43 @@ func getLiveStatus(videoIDs []string) (map[string]bool, error) {
 		}
 
 		for _, item := range ytResp.Items {
-			if item.Snippet.LiveBroadcastContent == "live" {
-				liveStatus[item.ID] = true
+			duration, _ := parseISODuration(item.ContentDetails.Duration)
+			views, _ := strconv.ParseInt(item.Statistics.ViewCount, 10, 64)
+			d := videoDetails{
+				IsLive:     item.Snippet.LiveBroadcastContent == "live",
+				WasLive:    item.LiveStreamingDetails != nil,
+				Duration:   duration,
+				Views:      views,
+				IsUpcoming: item.Snippet.LiveBroadcastContent == "upcoming",
+			}
+			if live := item.LiveStreamingDetails; live != nil {
+				d.ScheduledStart, _ = time.Parse(time.RFC3339, live.ScheduledStartTime)
+				d.LiveStart, _ = time.Parse(time.RFC3339, live.ActualStartTime)
 			}
+			details[item.ID] = d
 		}
 	}
 
-	return liveStatus, nil
+	return details, nil
+}
+
+var isoDurationRegex = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseISODuration parses the ISO 8601 durations used by the YouTube API,
+// such as "PT1H2M3S". Live streams report "P0D".
+func parseISODuration(s string) (time.Duration, error) {
+	matches := isoDurationRegex.FindStringSubmatch(s)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid duration: %q", s)
+	}
+
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var duration time.Duration
+	for i, unit := range units {
+		if matches[i+1] != "" {
+			n, _ := strconv.Atoi(matches[i+1])
+			duration += time.Duration(n) * unit
+		}
+	}
+	return duration, nil
 }
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"yt_rss2/templates"
+)
+
+// A viewQuery is a parsed saved view filter. Queries are a list of terms that
+// must all match, such as:
+//
+//	group:Tech duration:>20m age:<7d -is:watched
+//
+// A term is either free text, matched against the title, or key:value where
+// key is one of channel, group, title, age, duration or is. Values with
+// spaces can be quoted, and a leading "-" negates a term.
+type viewQuery struct {
+	terms []viewTerm
+}
+
+type viewTerm struct {
+	negate bool
+	key    string
+	value  string
+	// match reports whether a video satisfies the term, ignoring negation.
+	match func(item templates.VideoWithChannel, now time.Time) bool
+}
+
+// viewFlags are the values accepted by the is: key.
+var viewFlags = map[string]func(item templates.VideoWithChannel) bool{
+	"short":    isShort,
+	"live":     func(item templates.VideoWithChannel) bool { return item.IsLive },
+	"vod":      func(item templates.VideoWithChannel) bool { return item.WasLive && !item.IsLive },
+	"watched":  func(item templates.VideoWithChannel) bool { return item.Watched },
+	"priority": func(item templates.VideoWithChannel) bool { return item.Channel.Priority > 0 },
+}
+
+var viewTokenRegex = regexp.MustCompile(`-?(?:[a-z]+:)?(?:"[^"]*"|\S+)`)
+
+// parseViewQuery parses a saved view filter. An empty query matches every
+// video.
+func parseViewQuery(query string) (viewQuery, error) {
+	var q viewQuery
+	for _, token := range viewTokenRegex.FindAllString(query, -1) {
+		term, err := parseViewTerm(token)
+		if err != nil {
+			return viewQuery{}, err
+		}
+		q.terms = append(q.terms, term)
+	}
+	return q, nil
+}
+
+func parseViewTerm(token string) (viewTerm, error) {
+	var term viewTerm
+	if strings.HasPrefix(token, "-") && len(token) > 1 {
+		term.negate = true
+		token = token[1:]
+	}
+
+	key, value := "title", token
+	if k, v, ok := strings.Cut(token, ":"); ok && !strings.HasPrefix(token, `"`) {
+		key, value = k, v
+	}
+	if strings.HasPrefix(value, `"`) {
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			return term, fmt.Errorf("%s: unterminated quote", token)
+		}
+		value = value[1 : len(value)-1]
+	}
+	if value == "" {
+		return term, fmt.Errorf("%s: needs a value", key)
+	}
+	term.key = key
+	term.value = strings.ToLower(value)
+
+	switch key {
+	case "title":
+		text := strings.ToLower(value)
+		term.match = func(item templates.VideoWithChannel, _ time.Time) bool {
+			return strings.Contains(strings.ToLower(item.Item.Title), text)
+		}
+	case "channel":
+		text := strings.ToLower(value)
+		term.match = func(item templates.VideoWithChannel, _ time.Time) bool {
+			return strings.Contains(strings.ToLower(item.ChannelName), text) ||
+				strings.Contains(strings.ToLower(item.ChannelOriginalName), text)
+		}
+	case "group":
+		term.match = func(item templates.VideoWithChannel, _ time.Time) bool {
+			for _, group := range item.Channel.Groups {
+				if strings.EqualFold(group, value) {
+					return true
+				}
+			}
+			return false
+		}
+	case "age":
+		compare, err := parseViewComparison(key, value)
+		if err != nil {
+			return term, err
+		}
+		term.match = func(item templates.VideoWithChannel, now time.Time) bool {
+			return item.Item.PublishedParsed != nil && compare(now.Sub(*item.Item.PublishedParsed))
+		}
+	case "duration":
+		compare, err := parseViewComparison(key, value)
+		if err != nil {
+			return term, err
+		}
+		// Videos of unknown length, like ongoing live streams, never match.
+		term.match = func(item templates.VideoWithChannel, _ time.Time) bool {
+			return item.Duration > 0 && compare(item.Duration)
+		}
+	case "is":
+		flag, ok := viewFlags[term.value]
+		if !ok {
+			return term, fmt.Errorf("is:%s: unknown flag, expected short, live, vod, watched or priority", value)
+		}
+		term.match = func(item templates.VideoWithChannel, _ time.Time) bool {
+			return flag(item)
+		}
+	default:
+		return term, fmt.Errorf("%s: unknown key, expected channel, group, title, age, duration or is", key)
+	}
+	return term, nil
+}
+
+var viewSpanRegex = regexp.MustCompile(`^(?:\d+[smhdw])+$`)
+var viewSpanPartRegex = regexp.MustCompile(`(\d+)([smhdw])`)
+
+var viewSpanUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// parseViewComparison parses values like ">20m" or "<=1w2d" into a function
+// comparing a duration against them.
+func parseViewComparison(key, value string) (func(time.Duration) bool, error) {
+	op := strings.TrimRight(value, "0123456789smhdw")
+	span := value[len(op):]
+	if !viewSpanRegex.MatchString(span) {
+		return nil, fmt.Errorf("%s:%s: expected a span like 20m, 2h or 7d", key, value)
+	}
+
+	var limit time.Duration
+	for _, part := range viewSpanPartRegex.FindAllStringSubmatch(span, -1) {
+		n, _ := strconv.Atoi(part[1])
+		limit += time.Duration(n) * viewSpanUnits[part[2]]
+	}
+
+	switch op {
+	case "<":
+		return func(d time.Duration) bool { return d < limit }, nil
+	case "<=":
+		return func(d time.Duration) bool { return d <= limit }, nil
+	case ">":
+		return func(d time.Duration) bool { return d > limit }, nil
+	case ">=":
+		return func(d time.Duration) bool { return d >= limit }, nil
+	}
+	return nil, fmt.Errorf("%s:%s: expected one of <, <=, > or >= before the span", key, value)
+}
+
+// matches reports whether a video satisfies every term of the query.
+func (q viewQuery) matches(item templates.VideoWithChannel, now time.Time) bool {
+	for _, term := range q.terms {
+		if term.match(item, now) == term.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// decidesShorts reports whether the query filters on shorts itself, in which
+// case the feed's Show Shorts option shouldn't hide them first.
+func (q viewQuery) decidesShorts() bool {
+	for _, term := range q.terms {
+		if term.key == "is" && term.value == "short" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+
+	"github.com/gorilla/mux"
+)
+
+// ViewsHandler shows the popup for managing saved views.
+func ViewsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	renderViewsPopup(w, r, user.ID, "")
+}
+
+// AddViewHandler saves a new view.
+func AddViewHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	r.ParseForm()
+	name, query, viewError := viewFromForm(r)
+	if viewError != "" {
+		renderViewsPopup(w, r, user.ID, viewError)
+		return
+	}
+
+	err := database.DB.CreateView(user.ID, name, query)
+	if errors.Is(err, database.ErrDuplicate) {
+		renderViewsPopup(w, r, user.ID, "A view with that name already exists.")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to save view", http.StatusInternalServerError)
+		return
+	}
+
+	renderViewsPopup(w, r, user.ID, "")
+}
+
+// UpdateViewHandler renames a view and replaces its query.
+func UpdateViewHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	viewID, err := userViewID(user.ID, mux.Vars(r)["id"])
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	r.ParseForm()
+	name, query, viewError := viewFromForm(r)
+	if viewError != "" {
+		renderViewsPopup(w, r, user.ID, viewError)
+		return
+	}
+
+	err = database.DB.UpdateView(user.ID, templates.SavedView{ID: viewID, Name: name, Query: query})
+	if errors.Is(err, database.ErrDuplicate) {
+		renderViewsPopup(w, r, user.ID, "A view with that name already exists.")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to save view", http.StatusInternalServerError)
+		return
+	}
+
+	renderViewsPopup(w, r, user.ID, "")
+}
+
+// DeleteViewHandler removes a saved view.
+func DeleteViewHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	viewID, err := userViewID(user.ID, mux.Vars(r)["id"])
+	if err == database.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := database.DB.DeleteView(user.ID, viewID); err != nil {
+		http.Error(w, "Failed to delete view", http.StatusInternalServerError)
+		return
+	}
+
+	renderViewsPopup(w, r, user.ID, "")
+}
+
+// viewFromForm reads and checks a view's name and query, returning an error
+// message for the popup if they aren't usable.
+func viewFromForm(r *http.Request) (name, query, viewError string) {
+	name = strings.TrimSpace(r.FormValue("name"))
+	query = strings.TrimSpace(r.FormValue("query"))
+
+	if name == "" {
+		return "", "", "View name can't be empty."
+	}
+	if _, err := parseViewQuery(query); err != nil {
+		return "", "", "Invalid query: " + err.Error()
+	}
+	return name, query, ""
+}
+
+// renderViewsPopup renders the view manager along with an out-of-band
+// refresh of the view tabs above the feed.
+func renderViewsPopup(w http.ResponseWriter, r *http.Request, userID int, viewError string) {
+	views, err := database.DB.Views(userID)
+	if err != nil {
+		http.Error(w, "Failed to load views", http.StatusInternalServerError)
+		return
+	}
+
+	templates.ViewsPopup(views, viewError).Render(r.Context(), w)
+	if r.Method == http.MethodPost {
+		opts := loadFeedOptions(userID)
+		templates.ViewTabs(views, activeViewID(views, opts.View)).Render(r.Context(), w)
+	}
+}
+
+// renderViewTabs renders the view tabs out of band, with the given view
+// selected.
+func renderViewTabs(w http.ResponseWriter, r *http.Request, userID int, viewID int) {
+	views, err := database.DB.Views(userID)
+	if err != nil {
+		http.Error(w, "Failed to load views", http.StatusInternalServerError)
+		return
+	}
+	templates.ViewTabs(views, activeViewID(views, viewID)).Render(r.Context(), w)
+}
+
+// activeViewID returns viewID if it's one of views, and 0 otherwise, so a
+// deleted view falls back to the unfiltered feed.
+func activeViewID(views []templates.SavedView, viewID int) int {
+	for _, view := range views {
+		if view.ID == viewID {
+			return viewID
+		}
+	}
+	return 0
+}
+
+// userViewID checks that a view ID from the URL belongs to the user.
+func userViewID(userID int, rawID string) (int, error) {
+	viewID, err := strconv.Atoi(rawID)
+	if err != nil {
+		return 0, database.ErrNotFound
+	}
+	view, err := database.DB.View(userID, viewID)
+	return view.ID, err
+}
+
+// loadViewQuery loads and parses one of the user's saved views.
+func loadViewQuery(userID, viewID int) (viewQuery, error) {
+	view, err := database.DB.View(userID, viewID)
+	if err != nil {
+		return viewQuery{}, err
+	}
+	return parseViewQuery(view.Query)
+}
+
+// markWatchedVideos sets Watched on the videos the user has opened.
+func markWatchedVideos(userID int, items []templates.VideoWithChannel) error {
+	videoIDs, err := database.DB.WatchedVideoIDs(userID)
+	if err != nil {
+		return err
+	}
+
+	watched := make(map[string]bool)
+	for _, videoID := range videoIDs {
+		watched[videoID] = true
+	}
+
+	for i := range items {
+		items[i].Watched = watched[items[i].VideoID]
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+)
+
+// visitGap is how long a user has to be away before coming back counts as a
+// new visit.
+const visitGap = time.Hour
+
+// visitInfo is what's needed to place the feed in the user's own time.
+type visitInfo struct {
+	// Location is the user's timezone, as last reported by their browser.
+	Location *time.Location
+	// LastVisit is when the user's previous visit ended, or zero if this is
+	// their first.
+	LastVisit time.Time
+}
+
+// IndexHandler renders the feed page and records the visit.
+func IndexHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	if err := recordVisit(user.ID, time.Now()); err != nil {
+		log.Printf("Error recording visit for user %d: %v", user.ID, err)
+	}
+	templates.Layout(user, templates.IndexPage(r.URL.RawQuery)).Render(r.Context(), w)
+}
+
+// recordVisit notes that the user is active. If they were away for longer
+// than visitGap, the previous visit is over and its end becomes the cutoff
+// for what's new.
+func recordVisit(userID int, now time.Time) error {
+	return database.DB.RecordVisit(userID, now, visitGap)
+}
+
+// loadVisitInfo returns the user's timezone and the end of their previous
+// visit. Users whose browser never reported a timezone get UTC.
+func loadVisitInfo(userID int) visitInfo {
+	timezone, previousVisit, err := database.DB.VisitInfo(userID)
+	if err != nil {
+		log.Printf("Error loading visit info for user %d: %v", userID, err)
+	}
+
+	info := visitInfo{Location: time.UTC}
+	if loc, err := time.LoadLocation(timezone); err == nil && timezone != "" {
+		info.Location = loc
+	}
+	if previousVisit > 0 {
+		info.LastVisit = time.Unix(previousVisit, 0).In(info.Location)
+	}
+	return info
+}
+
+// saveTimezone stores the timezone the browser sends with htmx requests, if
+// it's a valid one.
+func saveTimezone(r *http.Request, userID int) {
+	timezone := r.Header.Get("X-Timezone")
+	if timezone == "" {
+		return
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return
+	}
+	if err := database.DB.SetTimezone(userID, timezone); err != nil {
+		log.Printf("Error saving timezone for user %d: %v", userID, err)
+	}
+}
+
+// publishedRange is the span of upload times the feed shows. Zero bounds are
+// open.
+type publishedRange struct {
+	From  time.Time
+	Until time.Time
+}
+
+// feedRange works out the upload times to show from the date range in the
+// feed form, taking whole days in the user's timezone, and the "only new"
+// toggle.
+func feedRange(opts feedOptions, visit visitInfo) publishedRange {
+	var published publishedRange
+	if day, err := time.ParseInLocation(dateFormat, opts.From, visit.Location); err == nil {
+		published.From = day
+	}
+	if day, err := time.ParseInLocation(dateFormat, opts.To, visit.Location); err == nil {
+		published.Until = day.AddDate(0, 0, 1)
+	}
+	if opts.OnlyNew && visit.LastVisit.After(published.From) {
+		published.From = visit.LastVisit
+	}
+	return published
+}
+
+// contains reports whether t falls within the range.
+func (p publishedRange) contains(t time.Time) bool {
+	if !p.From.IsZero() && t.Before(p.From) {
+		return false
+	}
+	if !p.Until.IsZero() && !t.Before(p.Until) {
+		return false
+	}
+	return true
+}
+
+// markNewDivider puts the "new since your last visit" divider before the
+// first video the user has already had the chance to see. It only makes
+// sense when the feed is newest first and old videos are shown at all.
+func markNewDivider(items []templates.VideoWithChannel, opts feedOptions, visit visitInfo) {
+	if opts.Sort != templates.SortNewest || opts.OnlyNew || visit.LastVisit.IsZero() {
+		return
+	}
+
+	label := "New since your last visit, " + visit.LastVisit.Format("Mon Jan 2 15:04")
+	for i := 1; i < len(items); i++ {
+		if items[i-1].IsNew && !items[i].IsNew {
+			items[i].NewDivider = label
+			return
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+	"yt_rss2/database"
+	"yt_rss2/templates"
+
+	"github.com/gorilla/mux"
+	"github.com/mmcdole/gofeed"
+)
+
+// WatchLaterHandler adds a video to the user's Watch Later queue, or takes it
+// off if it's already there, and renders the updated toggle button.
+func WatchLaterHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(templates.User)
+	videoID := mux.Vars(r)["id"]
+	if !youtubeVideoRegex.MatchString(videoID) {
+		http.Error(w, "Invalid video ID", http.StatusBadRequest)
+		return
+	}
+
+	queued, err := database.DB.InWatchLater(user.ID, videoID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := database.DB.SetWatchLater(user.ID, videoID, !queued, time.Now()); err != nil {
+		http.Error(w, "Failed to update Watch Later", http.StatusInternalServerError)
+		return
+	}
+
+	templates.WatchLaterButton(videoID, !queued).Render(r.Context(), w)
+}
+
+// loadWatchLater returns the user's Watch Later queue, oldest first. Details
+// come from the stored uploads; videos that aren't stored only have their ID.
+func loadWatchLater(userID int) ([]templates.VideoWithChannel, error) {
+	entries, err := database.DB.WatchLater(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var videos []templates.VideoWithChannel
+	for _, entry := range entries {
+		var video templates.VideoWithChannel
+		video.VideoID = entry.VideoID
+		video.ChannelID = entry.ChannelID
+
+		publishedAt := time.Unix(entry.PublishedAt, 0)
+		video.Item = &gofeed.Item{
+			Title:           entry.Title,
+			Link:            "https://www.youtube.com/watch?v=" + video.VideoID,
+			PublishedParsed: &publishedAt,
+		}
+		video.Duration = time.Duration(entry.DurationSeconds) * time.Second
+		video.Channel = templates.Channel{Name: entry.ChannelName, Alias: entry.ChannelAlias, ChannelID: video.ChannelID}
+		video.ChannelName = video.Channel.DisplayName()
+		video.ChannelOriginalName = entry.ChannelName
+		videos = append(videos, video)
+	}
+	return videos, nil
+}
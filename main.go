@@ -6,48 +6,161 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	_ "time/tzdata"
 	"yt_rss2/database"
 	"yt_rss2/handlers"
-	"yt_rss2/templates"
 
 	"github.com/gorilla/mux"
 	_ "yt_rss2/config"
 )
 
+const usage = `Usage: yt_rss2 [command]
+
+Commands:
+  serve [-port N]                   Run the web app. This is the default.
+  user add NAME                     Create a user, reading the password from stdin.
+  user list                         List the users.
+  user passwd NAME                  Set a user's password, reading it from stdin.
+  user delete NAME                  Delete a user and everything they saved.
+  channels import -user NAME FILE   Import a subscription file, or stdin for "-".
+  channels export -user NAME [-format FORMAT] [-settings] [FILE]
+                                    Export a user's subscriptions, to stdout
+                                    without FILE.
+  fetch                             Fetch every channel's feed now.
+  db migrate                        Bring the database schema up to date.
+  db backup FILE                    Write a consistent copy of a SQLite database.
+  tui -server URL -token TOKEN      Browse the feed in the terminal.
+  tui -user NAME                    Browse a user's feed straight from the
+                                    database, without a server.
+`
+
 func main() {
-	port := flag.Int("port", 0, "port to run the server on")
-	flag.Parse()
+	args := os.Args[1:]
+	// Flags without a command are for serve, as before there were commands.
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		serve(args)
+		return
+	}
+
+	var err error
+	switch command, args := args[0], args[1:]; command {
+	case "serve":
+		serve(args)
+	case "user":
+		err = userCommand(args)
+	case "channels":
+		err = channelsCommand(args)
+	case "fetch":
+		err = fetchCommand(args)
+	case "db":
+		err = dbCommand(args)
+	case "tui":
+		err = tuiCommand(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q.\n\n%s", command, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "yt_rss2:", err)
+		os.Exit(1)
+	}
+}
 
+// serve runs the web app.
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	port := flags.Int("port", 0, "port to run the server on")
+	flags.Parse(args)
+
+	handlers.InitSessionStore()
 	database.InitDB()
 
+	r := newRouter()
+
+	addr := ":" + strconv.Itoa(*port)
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Listening on port: " + strconv.Itoa(l.Addr().(*net.TCPAddr).Port))
+	log.Fatal(http.Serve(l, r))
+}
+
+// newRouter registers every route of the web app and the API.
+func newRouter() *mux.Router {
 	r := mux.NewRouter()
 
 	r.HandleFunc("/login", handlers.LoginHandler)
 	r.HandleFunc("/register", handlers.RegisterHandler)
 	r.HandleFunc("/logout", handlers.LogoutHandler)
+	// Feed readers can't log in, so output feeds authenticate with a token.
+	r.HandleFunc("/feed/{token}/{format:atom|rss|json}", handlers.FeedOutputHandler)
+	r.HandleFunc("/feed/{token}/calendar.ics", handlers.CalendarHandler)
+	r.HandleFunc("/playlist.m3u", handlers.PlaylistHandler)
+
+	// The JSON API authenticates with personal access tokens. It has to be
+	// registered before the session-authenticated routes, which match any
+	// path.
+	r.HandleFunc("/api/v1/openapi.yaml", handlers.APISpecHandler)
+	api := r.PathPrefix("/api/v1").Subrouter()
+	api.Use(handlers.APIAuthMiddleware)
+	api.NotFoundHandler = http.HandlerFunc(handlers.APINotFoundHandler)
+	registerAPIRoutes(api)
 
 	authRouter := r.PathPrefix("/").Subrouter()
 	authRouter.Use(handlers.AuthMiddleware)
 
-	authRouter.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		user := r.Context().Value("user").(templates.User)
-		templates.Layout(user, templates.IndexPage()).Render(r.Context(), w)
-	})
+	authRouter.HandleFunc("/", handlers.IndexHandler)
 	authRouter.HandleFunc("/videos", handlers.VideosHandler)
+	authRouter.HandleFunc("/videos/burst", handlers.BurstHandler)
 	authRouter.HandleFunc("/video/{id}", handlers.VideoPageHandler)
+	authRouter.HandleFunc("/watch-later/{id}", handlers.WatchLaterHandler).Methods("POST")
 	authRouter.HandleFunc("/channels", handlers.ChannelsHandler)
+	authRouter.HandleFunc("/channel/{id}", handlers.ChannelPageHandler)
+	authRouter.HandleFunc("/channel/{id}/videos", handlers.ChannelVideosHandler)
+	authRouter.HandleFunc("/channel/{id}/backfill", handlers.BackfillHandler).Methods("GET", "POST")
+	authRouter.HandleFunc("/avatar/{id}", handlers.AvatarHandler)
 	authRouter.HandleFunc("/export", handlers.ExportHandler)
+	authRouter.HandleFunc("/export/download", handlers.ExportDownloadHandler)
 	authRouter.HandleFunc("/import", handlers.ImportHandler)
+	authRouter.HandleFunc("/import/commit", handlers.ImportCommitHandler).Methods("POST")
+	authRouter.HandleFunc("/groups", handlers.GroupsHandler).Methods("GET")
+	authRouter.HandleFunc("/groups", handlers.AddGroupHandler).Methods("POST")
+	authRouter.HandleFunc("/groups/{id}", handlers.UpdateGroupHandler).Methods("POST")
+	authRouter.HandleFunc("/groups/{id}/delete", handlers.DeleteGroupHandler).Methods("POST")
+	authRouter.HandleFunc("/views", handlers.ViewsHandler).Methods("GET")
+	authRouter.HandleFunc("/views", handlers.AddViewHandler).Methods("POST")
+	authRouter.HandleFunc("/views/{id}", handlers.UpdateViewHandler).Methods("POST")
+	authRouter.HandleFunc("/views/{id}/delete", handlers.DeleteViewHandler).Methods("POST")
+	authRouter.HandleFunc("/feed-token", handlers.FeedTokenHandler).Methods("GET")
+	authRouter.HandleFunc("/feed-token", handlers.RegenerateFeedTokenHandler).Methods("POST")
+	authRouter.HandleFunc("/feed-token/revoke", handlers.RevokeFeedTokenHandler).Methods("POST")
+	authRouter.HandleFunc("/api-tokens", handlers.APITokensHandler).Methods("GET")
+	authRouter.HandleFunc("/api-tokens", handlers.AddAPITokenHandler).Methods("POST")
+	authRouter.HandleFunc("/api-tokens/{id}/delete", handlers.DeleteAPITokenHandler).Methods("POST")
 	authRouter.HandleFunc("/cycle-theme", handlers.CycleThemeHandler).Methods("POST")
 	authRouter.HandleFunc("/add-channel", handlers.AddChannelHandler).Methods("POST")
+	authRouter.HandleFunc("/bulk-add-channel", handlers.BulkAddChannelHandler)
+	authRouter.HandleFunc("/edit-channel", handlers.EditChannelHandler)
 	authRouter.HandleFunc("/delete-channel", handlers.DeleteChannelHandler).Methods("POST")
 
-	addr := ":" + strconv.Itoa(*port)
-	l, err := net.Listen("tcp", addr)
-	if err != nil {
-		log.Fatal(err)
-	}
-	fmt.Println("Listening on port: " + strconv.Itoa(l.Addr().(*net.TCPAddr).Port))
-	log.Fatal(http.Serve(l, r))
+	return r
+}
+
+// registerAPIRoutes adds the JSON API's routes to a router for /api/v1. The
+// router has to put the user in the context.
+func registerAPIRoutes(api *mux.Router) {
+	api.HandleFunc("/channels", handlers.APIChannelsHandler).Methods("GET")
+	api.HandleFunc("/channels", handlers.APIAddChannelHandler).Methods("POST")
+	api.HandleFunc("/channels/{id}", handlers.APIDeleteChannelHandler).Methods("DELETE")
+	api.HandleFunc("/feed", handlers.APIFeedHandler).Methods("GET")
+	api.HandleFunc("/watched", handlers.APIWatchedHandler).Methods("GET")
+	api.HandleFunc("/watched/{id}", handlers.APISetWatchedHandler).Methods("PUT", "DELETE")
+	api.HandleFunc("/settings", handlers.APISettingsHandler).Methods("GET")
+	api.HandleFunc("/settings", handlers.APIUpdateSettingsHandler).Methods("PATCH")
 }
@@ -44,7 +44,7 @@ func Layout(user User, content templ.Component) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<style>\n\t\t\t\t/* --- Design System (Shared) --- */\n\t\t\t\t:root {\n\t\t\t\t\t/* Typography */\n\t\t\t\t\t--font-sans: -apple-system, BlinkMacSystemFont, \"Segoe UI\", Roboto, Helvetica, Arial, sans-serif;\n\t\t\t\t\t\n\t\t\t\t\t/* Sizing & Spacing */\n\t\t\t\t\t--border-radius: 0.5rem;\n\t\t\t\t\t--shadow-sm: 0 1px 2px 0 rgb(0 0 0 / 0.1);\n\t\t\t\t\t--shadow-lg: 0 10px 15px -3px rgb(0 0 0 / 0.2), 0 4px 6px -4px rgb(0 0 0 / 0.2);\n\t\t\t\t\t--spacing-1: 0.25rem;\n\t\t\t\t\t--spacing-2: 0.5rem;\n\t\t\t\t\t--spacing-3: 1rem;\n\t\t\t\t\t--spacing-4: 1.5rem;\n\t\t\t\t\t--spacing-5: 2rem;\n\t\t\t\t}\n\n\t\t\t\t/* --- Base & Layout (Theme-agnostic) --- */\n\t\t\t\tbody {\n\t\t\t\t\tfont-family: var(--font-sans);\n\t\t\t\t\tbackground-color: var(--bg-primary);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tpadding: var(--spacing-5);\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tline-height: 1.5;\n\t\t\t\t}\n\n\t\t\t\tmain {\n\t\t\t\t\tmax-width: 960px;\n\t\t\t\t\twidth: 100%;\n\t\t\t\t}\n\n\t\t\t\th1 {\n\t\t\t\t\tfont-size: 2.25rem;\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t\ttext-align: center;\n\t\t\t\t\tmargin-bottom: var(--spacing-5);\n\t\t\t\t\tcursor: pointer;\n\t\t\t\t\tuser-select: none;\n\t\t\t\t}\n\n\t\t\t\ta {\n\t\t\t\t\tcolor: var(--accent-primary);\n\t\t\t\t\ttext-decoration: none;\n\t\t\t\t\ttransition: opacity 0.2s ease;\n\t\t\t\t}\n\t\t\t\ta:hover {\n\t\t\t\t\topacity: 0.8;\n\t\t\t\t\ttext-decoration: none;\n\t\t\t\t}\n\n\t\t\t\t/* --- Channels & Forms --- */\n\t\t\t\t#channels {\n\t\t\t\t\tmargin-bottom: var(--spacing-5);\n\t\t\t\t\tpadding: var(--spacing-4);\n\t\t\t\t\tbackground-color: var(--bg-secondary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t}\n\n\t\t\t\tfieldset {\n\t\t\t\t\tborder: none;\n\t\t\t\t\tpadding: 0;\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tmargin-bottom: var(--spacing-4);\n\t\t\t\t}\n\t\t\t\tfieldset:last-of-type {\n\t\t\t\t\tmargin-bottom: 0;\n\t\t\t\t}\n\n\t\t\t\tlegend {\n\t\t\t\t\tfont-size: 1.25rem;\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t\tmargin-bottom: var(--spacing-3);\n\t\t\t\t}\n\n\t\t\t\t#channels-list ul {\n\t\t\t\t\tlist-style: none;\n\t\t\t\t\tpadding: 0;\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-wrap: wrap;\n\t\t\t\t\tgap: var(--spacing-3);\n\t\t\t\t}\n\n\t\t\t\t#channels-list li {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t}\n\n\t\t\t\tinput[type=\"checkbox\"] {\n\t\t\t\t\twidth: 1.15em;\n\t\t\t\t\theight: 1.15em;\n\t\t\t\t\taccent-color: var(--accent-primary);\n\t\t\t\t}\n\n\t\t\t\tinput[type=\"text\"],\n\t\t\t\tinput[type=\"password\"] {\n\t\t\t\t\tpadding: var(--spacing-2) var(--spacing-3);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\tfont-size: 1rem;\n\t\t\t\t\tbackground-color: var(--bg-primary);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t}\n\n\t\t\t\tbutton, .button {\n\t\t\t\t\tpadding: var(--spacing-2) var(--spacing-3);\n\t\t\t\t\tborder: 1px solid transparent;\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\tfont-size: 1rem;\n\t\t\t\t\tfont-weight: 500;\n\t\t\t\t\tcursor: pointer;\n\t\t\t\t\ttransition: all 0.2s ease;\n\t\t\t\t}\n\n\t\t\t\tbutton[type=\"submit\"] {\n\t\t\t\t\tbackground-color: var(--accent-primary);\n\t\t\t\t\tcolor: var(--bg-primary);\n\t\t\t\t\tborder-color: var(--accent-primary);\n\t\t\t\t}\n\t\t\t\tbutton[type=\"submit\"]:hover {\n\t\t\t\t\topacity: 0.9;\n\t\t\t\t}\n\n\t\t\t\t.delete-btn {\n\t\t\t\t\tbackground-color: transparent;\n\t\t\t\t\tcolor: var(--accent-danger);\n\t\t\t\t\tpadding: var(--spacing-1);\n\t\t\t\t\tfont-size: 0.875rem;\n\t\t\t\t}\n\t\t\t\t.delete-btn:hover {\n\t\t\t\t\tbackground-color: var(--accent-danger);\n\t\t\t\t\tcolor: white;\n\t\t\t\t}\n\n\t\t\t\t.channels-container {\n\t\t\t\t\tposition: relative;\n\t\t\t\t}\n\n\t\t\t\t.channels-header {\n\t\t\t\t\tposition: absolute;\n\t\t\t\t\ttop: 0;\n\t\t\t\t\tright: 0;\n\t\t\t\t}\n\n\t\t\t\t.header-buttons {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t}\n\n\t\t\t\t.logout-btn, .header-buttons .button {\n\t\t\t\t\tbackground-color: var(--bg-primary);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t}\n\t\t\t\t.logout-btn:hover, .header-buttons .button:hover {\n\t\t\t\t\tbackground-color: var(--border-color);\n\t\t\t\t}\n\n\t\t\t\t/* --- Videos Grid --- */\n\t\t\t\t#videos {\n\t\t\t\t\tdisplay: grid;\n\t\t\t\t\tgrid-template-columns: repeat(auto-fill, minmax(300px, 1fr));\n\t\t\t\t\tgap: var(--spacing-4);\n\t\t\t\t}\n\n\t\t\t\t.video {\n\t\t\t\t\tbackground-color: var(--bg-secondary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\toverflow: hidden;\n\t\t\t\t\tbox-shadow: var(--shadow-sm);\n\t\t\t\t\ttransition: transform 0.2s ease, box-shadow 0.2s ease;\n\t\t\t\t}\n\t\t\t\t.video:hover {\n\t\t\t\t\ttransform: translateY(-5px);\n\t\t\t\t\tbox-shadow: var(--shadow-lg);\n\t\t\t\t}\n\n\t\t\t\t.video a {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\theight: 100%;\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t\tposition: relative; /* Needed for absolute positioning of the icon */\n\t\t\t\t}\n\t\t\t\t.video a:hover {\n\t\t\t\t\topacity: 1;\n\t\t\t\t}\n\n\t\t\t\t.thumbnail-container {\n\t\t\t\t\tposition: relative;\n\t\t\t\t}\n\n\t\t\t\t.live-icon {\n\t\t\t\t\tposition: absolute;\n\t\t\t\t\tbottom: 10px;\n\t\t\t\t\tleft: 10px;\n\t\t\t\t\tbackground-color: var(--accent-danger);\n\t\t\t\t\tcolor: white;\n\t\t\t\t\tpadding: 2px 8px;\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\tfont-size: 0.75rem;\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t\ttext-transform: uppercase;\n\t\t\t\t\tz-index: 1;\n\t\t\t\t}\n\n\t\t\t\t.video img {\n\t\t\t\t\twidth: 100%;\n\t\t\t\t\theight: 170px;\n\t\t\t\t\tobject-fit: cover;\n\t\t\t\t\tdisplay: block;\n\t\t\t\t\tborder-bottom: 1px solid var(--border-color);\n\t\t\t\t}\n\n\t\t\t\t.video-info {\n\t\t\t\t\tpadding: var(--spacing-3);\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\tjustify-content: space-between;\n\t\t\t\t\tflex-grow: 1;\n\t\t\t\t}\n\n\t\t\t\t.video-title {\n\t\t\t\t\tmargin: 0 0 var(--spacing-1) 0;\n\t\t\t\t\tfont-size: 1rem;\n\t\t\t\t\tline-height: 1.4;\n\t\t\t\t\tfont-weight: 500;\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t}\n\n\t\t\t\t.video-meta {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tjustify-content: space-between;\n\t\t\t\t\talign-items: center;\n\t\t\t\t}\n\n\t\t\t\t.channel-name, .upload-date {\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tfont-size: 0.875rem;\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t#load-more {\n\t\t\t\t\ttext-align: center;\n\t\t\t\t\tpadding: var(--spacing-4);\n\t\t\t\t\tfont-weight: 500;\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t}\n\n\t\t\t\t/* --- HTMX Loading Indicator --- */\n\t\t\t\t.htmx-indicator {\n\t\t\t\t\tposition: fixed;\n\t\t\t\t\ttop: 50%;\n\t\t\t\t\tleft: 50%;\n\t\t\t\t\ttransform: translate(-50%, -50%);\n\t\t\t\t\tz-index: 9999;\n\t\t\t\t\topacity: 0;\n\t\t\t\t\ttransition: opacity 200ms ease-in;\n\t\t\t\t\tpointer-events: none;\n\t\t\t\t}\n\t\t\t\t.htmx-request .htmx-indicator {\n\t\t\t\t\topacity: 1;\n\t\t\t\t\tpointer-events: auto;\n\t\t\t\t}\n\t\t\t\t.htmx-request.htmx-indicator {\n\t\t\t\t\topacity: 1;\n\t\t\t\t\tpointer-events: auto;\n\t\t\t\t}\n\t\t\t\t.spinner {\n\t\t\t\t\twidth: 60px;\n\t\t\t\t\theight: 60px;\n\t\t\t\t\tborder: 6px solid var(--text-secondary);\n\t\t\t\t\tborder-top-color: var(--accent-primary);\n\t\t\t\t\tborder-radius: 50%;\n\t\t\t\t\tanimation: spin 1s linear infinite;\n\t\t\t\t}\n\t\t\t\t@keyframes spin {\n\t\t\t\t\tto {\n\t\t\t\t\t\ttransform: rotate(360deg);\n\t\t\t\t\t}\n\t\t\t\t}\n\n\t\t\t\t/* --- Auth Page --- */\n\t\t\t\t.auth-container {\n\t\t\t\t\tmax-width: 400px;\n\t\t\t\t\tmargin: var(--spacing-5) auto;\n\t\t\t\t\tpadding: var(--spacing-5);\n\t\t\t\t\tbackground-color: var(--bg-secondary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t}\n\t\t\t\t.auth-container h2 {\n\t\t\t\t\ttext-align: center;\n\t\t\t\t\tmargin-bottom: var(--spacing-4);\n\t\t\t\t}\n\t\t\t\t.auth-container form {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\tgap: var(--spacing-3);\n\t\t\t\t}\n\t\t\t\t.auth-container .error {\n\t\t\t\t\tcolor: var(--accent-danger);\n\t\t\t\t\ttext-align: center;\n\t\t\t\t\tmargin: 0;\n\t\t\t\t}\n\t\t\t\t.auth-container p {\n\t\t\t\t\ttext-align: center;\n\t\t\t\t\tmargin-top: var(--spacing-4);\n\t\t\t\t}\n\n\t\t\t\t/* --- Video Page --- */\n\t\t\t\tbody:has(.full-screen-video-page) {\n\t\t\t\t\tpadding: 0;\n\t\t\t\t\toverflow-x: hidden;\n\t\t\t\t}\n\n\t\t\t\t.full-screen-video-page {\n\t\t\t\t\twidth: 100vw;\n\t\t\t\t\tposition: relative;\n\t\t\t\t\tleft: 50%;\n\t\t\t\t\ttransform: translateX(-50%);\n\t\t\t\t}\n\t\t\t\t.video-wrapper {\n\t\t\t\t\twidth: 100%;\n\t\t\t\t\theight: 100vh;\n\t\t\t\t\tbackground: #000;\n\t\t\t\t}\n\t\t\t\t.video-wrapper iframe {\n\t\t\t\t\twidth: 100%;\n\t\t\t\t\theight: 100%;\n\t\t\t\t\tborder: none;\n\t\t\t\t}\n\t\t\t\t.back-button-container {\n\t\t\t\t\ttext-align: center;\n\t\t\t\t\tpadding: var(--spacing-5);\n\t\t\t\t}\n\t\t\t\t.back-btn {\n\t\t\t\t\tbackground-color: var(--bg-secondary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t}\n\n\t\t\t\t/* --- Popup Modals --- */\n\t\t\t\t.popup-overlay {\n\t\t\t\t\tposition: fixed;\n\t\t\t\t\ttop: 0;\n\t\t\t\t\tleft: 0;\n\t\t\t\t\twidth: 100%;\n\t\t\t\t\theight: 100%;\n\t\t\t\t\tbackground: rgba(0, 0, 0, 0.7);\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tjustify-content: center;\n\t\t\t\t\tz-index: 2000;\n\t\t\t\t\tbackdrop-filter: blur(4px);\n\t\t\t\t}\n\t\t\t\t.popup-content {\n\t\t\t\t\tbackground: var(--bg-secondary);\n\t\t\t\t\tpadding: var(--spacing-4);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\twidth: 90%;\n\t\t\t\t\tmax-width: 600px;\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\tgap: var(--spacing-4);\n\t\t\t\t}\n\t\t\t\t.popup-content form {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\tgap: var(--spacing-4);\n\t\t\t\t}\n\t\t\t\t.popup-content h3 {\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tfont-size: 1.5rem;\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t}\n\t\t\t\t.popup-content textarea {\n\t\t\t\t\twidth: 100%;\n\t\t\t\t\tmin-height: 200px;\n\t\t\t\t\tresize: vertical;\n\t\t\t\t\tbackground: var(--bg-primary);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\tpadding: var(--spacing-2);\n\t\t\t\t\tfont-family: monospace;\n\t\t\t\t}\n\t\t\t\t.popup-buttons {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tjustify-content: flex-end;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t}\n\t\t\t\t.popup-content .close-btn {\n\t\t\t\t\tbackground-color: var(--bg-primary);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t}\n\t\t\t</style></head><body><!-- Global Loading Indicator --><div id=\"loading-spinner\" class=\"htmx-indicator\"><div class=\"spinner\"></div></div><main>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<style>\n\t\t\t\t/* --- Design System (Shared) --- */\n\t\t\t\t:root {\n\t\t\t\t\t/* Typography */\n\t\t\t\t\t--font-sans: -apple-system, BlinkMacSystemFont, \"Segoe UI\", Roboto, Helvetica, Arial, sans-serif;\n\t\t\t\t\t\n\t\t\t\t\t/* Sizing & Spacing */\n\t\t\t\t\t--border-radius: 0.5rem;\n\t\t\t\t\t--shadow-sm: 0 1px 2px 0 rgb(0 0 0 / 0.1);\n\t\t\t\t\t--shadow-lg: 0 10px 15px -3px rgb(0 0 0 / 0.2), 0 4px 6px -4px rgb(0 0 0 / 0.2);\n\t\t\t\t\t--spacing-1: 0.25rem;\n\t\t\t\t\t--spacing-2: 0.5rem;\n\t\t\t\t\t--spacing-3: 1rem;\n\t\t\t\t\t--spacing-4: 1.5rem;\n\t\t\t\t\t--spacing-5: 2rem;\n\t\t\t\t}\n\n\t\t\t\t/* --- Base & Layout (Theme-agnostic) --- */\n\t\t\t\tbody {\n\t\t\t\t\tfont-family: var(--font-sans);\n\t\t\t\t\tbackground-color: var(--bg-primary);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tpadding: var(--spacing-5);\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tline-height: 1.5;\n\t\t\t\t}\n\n\t\t\t\tmain {\n\t\t\t\t\tmax-width: 960px;\n\t\t\t\t\twidth: 100%;\n\t\t\t\t}\n\n\t\t\t\th1 {\n\t\t\t\t\tfont-size: 2.25rem;\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t\ttext-align: center;\n\t\t\t\t\tmargin-bottom: var(--spacing-5);\n\t\t\t\t\tcursor: pointer;\n\t\t\t\t\tuser-select: none;\n\t\t\t\t}\n\n\t\t\t\ta {\n\t\t\t\t\tcolor: var(--accent-primary);\n\t\t\t\t\ttext-decoration: none;\n\t\t\t\t\ttransition: opacity 0.2s ease;\n\t\t\t\t}\n\t\t\t\ta:hover {\n\t\t\t\t\topacity: 0.8;\n\t\t\t\t\ttext-decoration: none;\n\t\t\t\t}\n\n\t\t\t\t/* --- Channels & Forms --- */\n\t\t\t\t#channels {\n\t\t\t\t\tmargin-bottom: var(--spacing-5);\n\t\t\t\t\tpadding: var(--spacing-4);\n\t\t\t\t\tbackground-color: var(--bg-secondary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t}\n\n\t\t\t\tfieldset {\n\t\t\t\t\tborder: none;\n\t\t\t\t\tpadding: 0;\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tmargin-bottom: var(--spacing-4);\n\t\t\t\t}\n\t\t\t\tfieldset:last-of-type {\n\t\t\t\t\tmargin-bottom: 0;\n\t\t\t\t}\n\n\t\t\t\tlegend {\n\t\t\t\t\tfont-size: 1.25rem;\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t\tmargin-bottom: var(--spacing-3);\n\t\t\t\t}\n\n\t\t\t\t#channels-list ul {\n\t\t\t\t\tlist-style: none;\n\t\t\t\t\tpadding: 0;\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-wrap: wrap;\n\t\t\t\t\tgap: var(--spacing-3);\n\t\t\t\t}\n\n\t\t\t\t#channels-list li {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t}\n\n\t\t\t\t#channels-list label, .channel-name {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t}\n\n\t\t\t\t.channel-avatar {\n\t\t\t\t\twidth: 1.5rem;\n\t\t\t\t\theight: 1.5rem;\n\t\t\t\t\tborder-radius: 50%;\n\t\t\t\t\tobject-fit: cover;\n\t\t\t\t\tflex-shrink: 0;\n\t\t\t\t}\n\n\t\t\t\t.manage-groups-btn, .select-group-btn {\n\t\t\t\t\tbackground-color: var(--bg-primary);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tfont-size: 0.875rem;\n\t\t\t\t\tpadding: var(--spacing-1) var(--spacing-2);\n\t\t\t\t}\n\t\t\t\t.manage-groups-btn {\n\t\t\t\t\tmargin-bottom: var(--spacing-3);\n\t\t\t\t}\n\n\t\t\t\t.channel-group {\n\t\t\t\t\tmargin-bottom: var(--spacing-3);\n\t\t\t\t}\n\n\t\t\t\t.view-tabs {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-wrap: wrap;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t\tmargin-bottom: var(--spacing-3);\n\t\t\t\t}\n\t\t\t\t.view-tab {\n\t\t\t\t\tbackground-color: var(--bg-secondary);\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\tpadding: var(--spacing-1) var(--spacing-3);\n\t\t\t\t\tcursor: pointer;\n\t\t\t\t\tfont-size: 0.875rem;\n\t\t\t\t}\n\t\t\t\t.view-tab.active {\n\t\t\t\t\tbackground-color: var(--accent-primary);\n\t\t\t\t\tcolor: var(--bg-primary);\n\t\t\t\t\tborder-color: var(--accent-primary);\n\t\t\t\t}\n\t\t\t\t.manage-views-btn {\n\t\t\t\t\tbackground-color: var(--bg-primary);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tfont-size: 0.875rem;\n\t\t\t\t\tpadding: var(--spacing-1) var(--spacing-2);\n\t\t\t\t\tmargin-left: auto;\n\t\t\t\t}\n\t\t\t\t.channel-group summary {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t\tcursor: pointer;\n\t\t\t\t\tmargin-bottom: var(--spacing-2);\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t}\n\t\t\t\t.channel-group summary::before {\n\t\t\t\t\tcontent: \"▸\";\n\t\t\t\t}\n\t\t\t\t.channel-group[open] summary::before {\n\t\t\t\t\tcontent: \"▾\";\n\t\t\t\t}\n\n\t\t\t\tinput[type=\"checkbox\"] {\n\t\t\t\t\twidth: 1.15em;\n\t\t\t\t\theight: 1.15em;\n\t\t\t\t\taccent-color: var(--accent-primary);\n\t\t\t\t}\n\n\t\t\t\tinput[type=\"text\"],\n\t\t\t\tinput[type=\"password\"],\n\t\t\t\tselect {\n\t\t\t\t\tpadding: var(--spacing-2) var(--spacing-3);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\tfont-size: 1rem;\n\t\t\t\t\tbackground-color: var(--bg-primary);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t}\n\n\t\t\t\tbutton, .button {\n\t\t\t\t\tpadding: var(--spacing-2) var(--spacing-3);\n\t\t\t\t\tborder: 1px solid transparent;\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\tfont-size: 1rem;\n\t\t\t\t\tfont-weight: 500;\n\t\t\t\t\tcursor: pointer;\n\t\t\t\t\ttransition: all 0.2s ease;\n\t\t\t\t}\n\n\t\t\t\tbutton[type=\"submit\"] {\n\t\t\t\t\tbackground-color: var(--accent-primary);\n\t\t\t\t\tcolor: var(--bg-primary);\n\t\t\t\t\tborder-color: var(--accent-primary);\n\t\t\t\t}\n\t\t\t\tbutton[type=\"submit\"]:hover {\n\t\t\t\t\topacity: 0.9;\n\t\t\t\t}\n\n\t\t\t\t.edit-btn {\n\t\t\t\t\tbackground-color: transparent;\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t\tpadding: var(--spacing-1);\n\t\t\t\t\tfont-size: 0.875rem;\n\t\t\t\t}\n\t\t\t\t.edit-btn:hover {\n\t\t\t\t\tbackground-color: var(--border-color);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t}\n\n\t\t\t\t.delete-btn {\n\t\t\t\t\tbackground-color: transparent;\n\t\t\t\t\tcolor: var(--accent-danger);\n\t\t\t\t\tpadding: var(--spacing-1);\n\t\t\t\t\tfont-size: 0.875rem;\n\t\t\t\t}\n\t\t\t\t.delete-btn:hover {\n\t\t\t\t\tbackground-color: var(--accent-danger);\n\t\t\t\t\tcolor: white;\n\t\t\t\t}\n\n\t\t\t\t.channels-container {\n\t\t\t\t\tposition: relative;\n\t\t\t\t}\n\n\t\t\t\t.channels-header {\n\t\t\t\t\tposition: absolute;\n\t\t\t\t\ttop: 0;\n\t\t\t\t\tright: 0;\n\t\t\t\t}\n\n\t\t\t\t.header-buttons {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t}\n\n\t\t\t\t.logout-btn, .header-buttons .button {\n\t\t\t\t\tbackground-color: var(--bg-primary);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t}\n\t\t\t\t.logout-btn:hover, .header-buttons .button:hover {\n\t\t\t\t\tbackground-color: var(--border-color);\n\t\t\t\t}\n\n\t\t\t\t/* --- Videos Grid --- */\n\t\t\t\t#videos {\n\t\t\t\t\tdisplay: grid;\n\t\t\t\t\tgrid-template-columns: repeat(auto-fill, minmax(300px, 1fr));\n\t\t\t\t\tgap: var(--spacing-4);\n\t\t\t\t}\n\n\t\t\t\t.video {\n\t\t\t\t\tbackground-color: var(--bg-secondary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\toverflow: hidden;\n\t\t\t\t\tbox-shadow: var(--shadow-sm);\n\t\t\t\t\ttransition: transform 0.2s ease, box-shadow 0.2s ease;\n\t\t\t\t}\n\t\t\t\t.video:hover {\n\t\t\t\t\ttransform: translateY(-5px);\n\t\t\t\t\tbox-shadow: var(--shadow-lg);\n\t\t\t\t}\n\n\t\t\t\t.video.watched {\n\t\t\t\t\topacity: 0.6;\n\t\t\t\t}\n\n\t\t\t\t.new-divider {\n\t\t\t\t\tgrid-column: 1 / -1;\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tgap: var(--spacing-3);\n\t\t\t\t\tcolor: var(--accent-primary);\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t}\n\t\t\t\t.new-divider::before, .new-divider::after {\n\t\t\t\t\tcontent: \"\";\n\t\t\t\t\tflex: 1;\n\t\t\t\t\tborder-top: 1px solid var(--accent-primary);\n\t\t\t\t}\n\n\t\t\t\t.date-range {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t}\n\n\t\t\t\t.burst-more {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tjustify-content: center;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t\tfont: inherit;\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t\tcursor: pointer;\n\t\t\t\t\tmin-height: 150px;\n\t\t\t\t}\n\t\t\t\t.burst-more .channel-avatar {\n\t\t\t\t\twidth: 3rem;\n\t\t\t\t\theight: 3rem;\n\t\t\t\t}\n\n\t\t\t\t.video a {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\theight: 100%;\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t\tposition: relative; /* Needed for absolute positioning of the icon */\n\t\t\t\t}\n\t\t\t\t.video a:hover {\n\t\t\t\t\topacity: 1;\n\t\t\t\t}\n\n\t\t\t\t.thumbnail-container {\n\t\t\t\t\tposition: relative;\n\t\t\t\t}\n\n\t\t\t\t.live-icon {\n\t\t\t\t\tposition: absolute;\n\t\t\t\t\tbottom: 10px;\n\t\t\t\t\tleft: 10px;\n\t\t\t\t\tbackground-color: var(--accent-danger);\n\t\t\t\t\tcolor: white;\n\t\t\t\t\tpadding: 2px 8px;\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\tfont-size: 0.75rem;\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t\ttext-transform: uppercase;\n\t\t\t\t\tz-index: 1;\n\t\t\t\t}\n\n\t\t\t\t.video .thumbnail-container img {\n\t\t\t\t\twidth: 100%;\n\t\t\t\t\theight: 170px;\n\t\t\t\t\tobject-fit: cover;\n\t\t\t\t\tdisplay: block;\n\t\t\t\t\tborder-bottom: 1px solid var(--border-color);\n\t\t\t\t}\n\n\t\t\t\t.video-info {\n\t\t\t\t\tpadding: var(--spacing-3);\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\tjustify-content: space-between;\n\t\t\t\t\tflex-grow: 1;\n\t\t\t\t}\n\n\t\t\t\t.video-title {\n\t\t\t\t\tmargin: 0 0 var(--spacing-1) 0;\n\t\t\t\t\tfont-size: 1rem;\n\t\t\t\t\tline-height: 1.4;\n\t\t\t\t\tfont-weight: 500;\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t}\n\n\t\t\t\t.video-meta {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tjustify-content: space-between;\n\t\t\t\t\talign-items: center;\n\t\t\t\t}\n\n\t\t\t\t.channel-name, .upload-date {\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tfont-size: 0.875rem;\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\t#load-more {\n\t\t\t\t\ttext-align: center;\n\t\t\t\t\tpadding: var(--spacing-4);\n\t\t\t\t\tfont-weight: 500;\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t}\n\n\t\t\t\t/* --- HTMX Loading Indicator --- */\n\t\t\t\t.htmx-indicator {\n\t\t\t\t\tposition: fixed;\n\t\t\t\t\ttop: 50%;\n\t\t\t\t\tleft: 50%;\n\t\t\t\t\ttransform: translate(-50%, -50%);\n\t\t\t\t\tz-index: 9999;\n\t\t\t\t\topacity: 0;\n\t\t\t\t\ttransition: opacity 200ms ease-in;\n\t\t\t\t\tpointer-events: none;\n\t\t\t\t}\n\t\t\t\t.htmx-request .htmx-indicator {\n\t\t\t\t\topacity: 1;\n\t\t\t\t\tpointer-events: auto;\n\t\t\t\t}\n\t\t\t\t.htmx-request.htmx-indicator {\n\t\t\t\t\topacity: 1;\n\t\t\t\t\tpointer-events: auto;\n\t\t\t\t}\n\t\t\t\t.spinner {\n\t\t\t\t\twidth: 60px;\n\t\t\t\t\theight: 60px;\n\t\t\t\t\tborder: 6px solid var(--text-secondary);\n\t\t\t\t\tborder-top-color: var(--accent-primary);\n\t\t\t\t\tborder-radius: 50%;\n\t\t\t\t\tanimation: spin 1s linear infinite;\n\t\t\t\t}\n\t\t\t\t@keyframes spin {\n\t\t\t\t\tto {\n\t\t\t\t\t\ttransform: rotate(360deg);\n\t\t\t\t\t}\n\t\t\t\t}\n\n\t\t\t\t/* --- Auth Page --- */\n\t\t\t\t.auth-container {\n\t\t\t\t\tmax-width: 400px;\n\t\t\t\t\tmargin: var(--spacing-5) auto;\n\t\t\t\t\tpadding: var(--spacing-5);\n\t\t\t\t\tbackground-color: var(--bg-secondary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t}\n\t\t\t\t.auth-container h2 {\n\t\t\t\t\ttext-align: center;\n\t\t\t\t\tmargin-bottom: var(--spacing-4);\n\t\t\t\t}\n\t\t\t\t.auth-container form {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\tgap: var(--spacing-3);\n\t\t\t\t}\n\t\t\t\t.auth-container .error {\n\t\t\t\t\tcolor: var(--accent-danger);\n\t\t\t\t\ttext-align: center;\n\t\t\t\t\tmargin: 0;\n\t\t\t\t}\n\t\t\t\t.auth-container p {\n\t\t\t\t\ttext-align: center;\n\t\t\t\t\tmargin-top: var(--spacing-4);\n\t\t\t\t}\n\n\t\t\t\t/* --- Video Page --- */\n\t\t\t\tbody:has(.full-screen-video-page) {\n\t\t\t\t\tpadding: 0;\n\t\t\t\t\toverflow-x: hidden;\n\t\t\t\t}\n\n\t\t\t\t.full-screen-video-page {\n\t\t\t\t\twidth: 100vw;\n\t\t\t\t\tposition: relative;\n\t\t\t\t\tleft: 50%;\n\t\t\t\t\ttransform: translateX(-50%);\n\t\t\t\t}\n\t\t\t\t.video-wrapper {\n\t\t\t\t\twidth: 100%;\n\t\t\t\t\theight: 100vh;\n\t\t\t\t\tbackground: #000;\n\t\t\t\t}\n\t\t\t\t.video-wrapper iframe {\n\t\t\t\t\twidth: 100%;\n\t\t\t\t\theight: 100%;\n\t\t\t\t\tborder: none;\n\t\t\t\t}\n\t\t\t\t.back-button-container {\n\t\t\t\t\ttext-align: center;\n\t\t\t\t\tpadding: var(--spacing-5);\n\t\t\t\t}\n\t\t\t\t.back-btn {\n\t\t\t\t\tbackground-color: var(--bg-secondary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t}\n\n\t\t\t\t/* --- Channel Page --- */\n\t\t\t\t.channel-link {\n\t\t\t\t\tcolor: inherit;\n\t\t\t\t}\n\t\t\t\t.channel-link:hover {\n\t\t\t\t\ttext-decoration: underline;\n\t\t\t\t}\n\t\t\t\t.channel-page .back-button-container {\n\t\t\t\t\ttext-align: left;\n\t\t\t\t\tpadding: 0 0 var(--spacing-4) 0;\n\t\t\t\t}\n\t\t\t\t.channel-page-header {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tgap: var(--spacing-3);\n\t\t\t\t}\n\t\t\t\t.channel-page-header .channel-avatar {\n\t\t\t\t\twidth: 5rem;\n\t\t\t\t\theight: 5rem;\n\t\t\t\t}\n\t\t\t\t.channel-page-header h2, .channel-original-name {\n\t\t\t\t\tmargin: 0;\n\t\t\t\t}\n\t\t\t\t.channel-original-name, .channel-note {\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t}\n\t\t\t\t.channel-page-panels {\n\t\t\t\t\tdisplay: grid;\n\t\t\t\t\tgrid-template-columns: repeat(auto-fit, minmax(250px, 1fr));\n\t\t\t\t\tgap: var(--spacing-4);\n\t\t\t\t\tmargin: var(--spacing-4) 0;\n\t\t\t\t}\n\t\t\t\t.channel-panel {\n\t\t\t\t\tbackground-color: var(--bg-secondary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\tpadding: var(--spacing-3);\n\t\t\t\t}\n\t\t\t\t.channel-panel h3 {\n\t\t\t\t\tmargin-top: 0;\n\t\t\t\t}\n\t\t\t\t.channel-panel dl {\n\t\t\t\t\tdisplay: grid;\n\t\t\t\t\tgrid-template-columns: auto 1fr;\n\t\t\t\t\tgap: var(--spacing-1) var(--spacing-3);\n\t\t\t\t\tmargin: 0 0 var(--spacing-3) 0;\n\t\t\t\t}\n\t\t\t\t.channel-panel dt {\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t}\n\t\t\t\t.channel-panel dd {\n\t\t\t\t\tmargin: 0;\n\t\t\t\t}\n\t\t\t\t.backfill-status progress {\n\t\t\t\t\twidth: 100%;\n\t\t\t\t\taccent-color: var(--accent-primary);\n\t\t\t\t}\n\t\t\t\t.backfill-unavailable {\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t\tfont-size: 0.875rem;\n\t\t\t\t}\n\t\t\t\t.feed-failing, .feed-error {\n\t\t\t\t\tcolor: var(--accent-danger);\n\t\t\t\t}\n\n\t\t\t\t/* --- Popup Modals --- */\n\t\t\t\t.popup-overlay {\n\t\t\t\t\tposition: fixed;\n\t\t\t\t\ttop: 0;\n\t\t\t\t\tleft: 0;\n\t\t\t\t\twidth: 100%;\n\t\t\t\t\theight: 100%;\n\t\t\t\t\tbackground: rgba(0, 0, 0, 0.7);\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tjustify-content: center;\n\t\t\t\t\tz-index: 2000;\n\t\t\t\t\tbackdrop-filter: blur(4px);\n\t\t\t\t}\n\t\t\t\t.popup-content {\n\t\t\t\t\tbackground: var(--bg-secondary);\n\t\t\t\t\tpadding: var(--spacing-4);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\twidth: 90%;\n\t\t\t\t\tmax-width: 600px;\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\tgap: var(--spacing-4);\n\t\t\t\t}\n\t\t\t\t.popup-content form {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\tgap: var(--spacing-4);\n\t\t\t\t}\n\t\t\t\t.popup-content h3 {\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tfont-size: 1.5rem;\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t}\n\t\t\t\t.popup-content textarea {\n\t\t\t\t\twidth: 100%;\n\t\t\t\t\tmin-height: 200px;\n\t\t\t\t\tresize: vertical;\n\t\t\t\t\tbackground: var(--bg-primary);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t\tpadding: var(--spacing-2);\n\t\t\t\t\tfont-family: monospace;\n\t\t\t\t}\n\t\t\t\t.bulk-add-report {\n\t\t\t\t\tlist-style: none;\n\t\t\t\t\tpadding: 0;\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tmax-height: 200px;\n\t\t\t\t\toverflow-y: auto;\n\t\t\t\t}\n\t\t\t\t.bulk-add-report li {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t}\n\t\t\t\t.bulk-add-status {\n\t\t\t\t\tmin-width: 6rem;\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t}\n\t\t\t\t.bulk-add-added .bulk-add-status {\n\t\t\t\t\tcolor: var(--accent-primary);\n\t\t\t\t}\n\t\t\t\t.bulk-add-not-found .bulk-add-status, .bulk-add-failed .bulk-add-status {\n\t\t\t\t\tcolor: var(--accent-danger);\n\t\t\t\t}\n\t\t\t\t.bulk-add-name {\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t}\n\t\t\t\t.export-formats {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-wrap: wrap;\n\t\t\t\t\tgap: var(--spacing-3);\n\t\t\t\t}\n\t\t\t\t.export-formats legend {\n\t\t\t\t\twidth: 100%;\n\t\t\t\t}\n\t\t\t\t.export-formats label {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tgap: var(--spacing-1);\n\t\t\t\t}\n\t\t\t\t.group-add-form, .group-editor-header {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: row;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t}\n\t\t\t\t.group-editors {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\tgap: var(--spacing-3);\n\t\t\t\t\tmax-height: 400px;\n\t\t\t\t\toverflow-y: auto;\n\t\t\t\t}\n\t\t\t\t.popup-content .group-editor {\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t\tpadding: var(--spacing-3);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t\tborder-radius: var(--border-radius);\n\t\t\t\t}\n\t\t\t\t.group-editor ul {\n\t\t\t\t\tlist-style: none;\n\t\t\t\t\tpadding: 0;\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-wrap: wrap;\n\t\t\t\t\tgap: var(--spacing-2) var(--spacing-3);\n\t\t\t\t}\n\t\t\t\t.form-field {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\tgap: var(--spacing-1);\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t}\n\t\t\t\t.popup-content .form-field textarea {\n\t\t\t\t\tmin-height: 100px;\n\t\t\t\t\tfont-family: var(--font-sans);\n\t\t\t\t}\n\t\t\t\t.popup-content .error {\n\t\t\t\t\tcolor: var(--accent-danger);\n\t\t\t\t\tmargin: 0;\n\t\t\t\t}\n\t\t\t\t.import-preview {\n\t\t\t\t\tlist-style: none;\n\t\t\t\t\tpadding: 0;\n\t\t\t\t\tmargin: 0;\n\t\t\t\t\tmax-height: 300px;\n\t\t\t\t\toverflow-y: auto;\n\t\t\t\t}\n\t\t\t\t.import-preview li {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\talign-items: center;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t}\n\t\t\t\t.import-preview li:not(.import-new) {\n\t\t\t\t\tpadding-left: calc(1.15em + var(--spacing-2));\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t}\n\t\t\t\t.import-status {\n\t\t\t\t\tdisplay: inline-block;\n\t\t\t\t\tmin-width: 6rem;\n\t\t\t\t\tfont-weight: 600;\n\t\t\t\t}\n\t\t\t\t.import-rejected .import-status, .import-reason {\n\t\t\t\t\tcolor: var(--accent-danger);\n\t\t\t\t}\n\t\t\t\t.import-file {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tflex-direction: column;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t\tfont-size: 0.875rem;\n\t\t\t\t}\n\t\t\t\t.view-help {\n\t\t\t\t\tcolor: var(--text-secondary);\n\t\t\t\t\tfont-size: 0.875rem;\n\t\t\t\t\tmargin: 0;\n\t\t\t\t}\n\t\t\t\t.feed-urls th {\n\t\t\t\t\ttext-align: left;\n\t\t\t\t\tfont-weight: 500;\n\t\t\t\t\tpadding-right: var(--spacing-3);\n\t\t\t\t}\n\t\t\t\t.feed-urls td {\n\t\t\t\t\tpadding: 0 var(--spacing-2);\n\t\t\t\t}\n\t\t\t\t.api-token-new code {\n\t\t\t\t\tdisplay: block;\n\t\t\t\t\tmargin-top: var(--spacing-2);\n\t\t\t\t\tword-break: break-all;\n\t\t\t\t\tuser-select: all;\n\t\t\t\t}\n\t\t\t\t.view-editor {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t}\n\t\t\t\t.view-editor input[name=\"query\"] {\n\t\t\t\t\tflex: 1;\n\t\t\t\t}\n\t\t\t\t.popup-buttons {\n\t\t\t\t\tdisplay: flex;\n\t\t\t\t\tjustify-content: flex-end;\n\t\t\t\t\tgap: var(--spacing-2);\n\t\t\t\t}\n\t\t\t\t.popup-content .close-btn {\n\t\t\t\t\tbackground-color: var(--bg-primary);\n\t\t\t\t\tcolor: var(--text-primary);\n\t\t\t\t\tborder: 1px solid var(--border-color);\n\t\t\t\t}\n\t\t\t</style><script>\n\t\t\t\t// A channel in several groups has a checkbox in each group's\n\t\t\t\t// section; keep them in step.\n\t\t\t\tfunction syncChannelCheckboxes(checkbox) {\n\t\t\t\t\tcheckbox.form.querySelectorAll('input[name=\"channel\"]').forEach(input => {\n\t\t\t\t\t\tif (input.value === checkbox.value) {\n\t\t\t\t\t\t\tinput.checked = checkbox.checked;\n\t\t\t\t\t\t}\n\t\t\t\t\t});\n\t\t\t\t}\n\n\t\t\t\t// Select exactly the channels of one group and reload the feed.\n\t\t\t\tfunction selectChannelGroup(event, button) {\n\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\tconst form = button.closest('form');\n\t\t\t\t\tconst section = button.closest('details');\n\t\t\t\t\tconst urls = new Set(Array.from(section.querySelectorAll('input[name=\"channel\"]'), input => input.value));\n\t\t\t\t\tform.querySelectorAll('input[name=\"channel\"]').forEach(input => {\n\t\t\t\t\t\tinput.checked = urls.has(input.value);\n\t\t\t\t\t});\n\t\t\t\t\tform.dispatchEvent(new Event('change', { bubbles: true }));\n\t\t\t\t}\n\n\t\t\t\t// Let the server work out dates in the user's own timezone.\n\t\t\t\tdocument.addEventListener('htmx:configRequest', event => {\n\t\t\t\t\tevent.detail.headers['X-Timezone'] = Intl.DateTimeFormat().resolvedOptions().timeZone;\n\t\t\t\t});\n\n\t\t\t\t// Switch the feed to a saved view, or to no view for 0.\n\t\t\t\tfunction selectFeedView(tab, viewID) {\n\t\t\t\t\tdocument.getElementById('feed-view').value = viewID;\n\t\t\t\t\ttab.parentElement.querySelectorAll('.view-tab').forEach(other => {\n\t\t\t\t\t\tother.classList.toggle('active', other === tab);\n\t\t\t\t\t});\n\t\t\t\t\tconst form = document.getElementById('channels-list');\n\t\t\t\t\tform.dispatchEvent(new Event('change', { bubbles: true }));\n\t\t\t\t}\n\t\t\t</script></head><body><!-- Global Loading Indicator --><div id=\"loading-spinner\" class=\"htmx-indicator\"><div class=\"spinner\"></div></div><main>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -0,0 +1,541 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"yt_rss2/api"
+	"yt_rss2/client"
+	"yt_rss2/database"
+	"yt_rss2/handlers"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/term"
+)
+
+// tuiPageSize is how many videos the TUI loads at a time.
+const tuiPageSize = 100
+
+const tuiHelp = "↑/↓ move  enter play  w watched  c channels  s shorts  r reload  q quit"
+
+// tuiCommand browses the feed in the terminal. It talks to a server's JSON
+// API, or with -user calls the API handlers in this process, straight
+// against the local database.
+func tuiCommand(args []string) error {
+	flags := flag.NewFlagSet("tui", flag.ExitOnError)
+	server := flags.String("server", os.Getenv("YT_RSS_SERVER"), "URL of the server, or $YT_RSS_SERVER")
+	token := flags.String("token", os.Getenv("YT_RSS_TOKEN"), "personal access token for the server, or $YT_RSS_TOKEN")
+	username := flags.String("user", "", "browse this user's feed from the local database instead of a server")
+	player := flags.String("player", os.Getenv("YT_RSS_PLAYER"), `command to play videos with, the video URL added to the end, or $YT_RSS_PLAYER (default "mpv")`)
+	flags.Parse(args)
+
+	if *player == "" {
+		*player = "mpv"
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("tui needs a terminal")
+	}
+
+	var apiClient *client.Client
+	switch {
+	case *username != "":
+		database.InitDB()
+		var err error
+		apiClient, err = localClient(*username)
+		if err != nil {
+			return err
+		}
+		// The handlers log fetch errors, which would scribble over the screen.
+		log.SetOutput(io.Discard)
+	case *server != "" && *token != "":
+		apiClient = client.New(*server, *token)
+	default:
+		return errors.New("tui needs -server and -token, or -user to browse the local database")
+	}
+
+	ui := &feedUI{client: apiClient, player: strings.Fields(*player), selectedChannels: make(map[string]bool)}
+	return ui.run(context.Background())
+}
+
+// localClient returns an API client that calls the API handlers directly as
+// the given user, so no server or token is needed.
+func localClient(username string) (*client.Client, error) {
+	user, _, err := database.DB.UserByName(username)
+	if err == database.ErrNotFound {
+		return nil, fmt.Errorf("no user named %s", username)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api/v1").Subrouter()
+	api.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), "user", user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	api.NotFoundHandler = http.HandlerFunc(handlers.APINotFoundHandler)
+	registerAPIRoutes(api)
+
+	return &client.Client{BaseURL: "http://local", HTTPClient: &http.Client{Transport: handlerTransport{r}}}, nil
+}
+
+// handlerTransport answers HTTP requests by calling a handler in-process.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil {
+		req.Body = http.NoBody
+	}
+	response := &bufferedResponse{header: http.Header{}}
+	t.handler.ServeHTTP(response, req)
+	if response.status == 0 {
+		response.status = http.StatusOK
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.status, http.StatusText(response.status)),
+		StatusCode:    response.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        response.header,
+		Body:          io.NopCloser(&response.body),
+		ContentLength: int64(response.body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// bufferedResponse is an http.ResponseWriter that keeps the response in
+// memory.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *bufferedResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *bufferedResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *bufferedResponse) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(data)
+}
+
+// feedUI is the state of the terminal UI.
+type feedUI struct {
+	client *client.Client
+	player []string
+
+	videos []api.Video
+	// nextCursor continues the feed; it's empty once it's all loaded.
+	nextCursor string
+	selected   int
+	offset     int
+
+	channels []api.Channel
+	// selectedChannels are the channel IDs the feed is limited to. Empty
+	// means all channels.
+	selectedChannels map[string]bool
+	showShorts       bool
+	// pickingChannels is set while the channel list is shown instead of the
+	// feed.
+	pickingChannels bool
+	channel         int
+	channelOffset   int
+
+	status string
+}
+
+func (ui *feedUI) run(ctx context.Context) error {
+	fd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, oldState)
+	// Use the alternate screen and hide the cursor while running.
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	ui.status = "Loading…"
+	ui.draw()
+	ui.channels, err = ui.client.ListChannels(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(ui.channels, func(i, j int) bool {
+		return strings.ToLower(channelLabel(ui.channels[i])) < strings.ToLower(channelLabel(ui.channels[j]))
+	})
+	// Start from the filter the user last used in the app.
+	if settings, err := ui.client.GetSettings(ctx); err == nil {
+		ui.showShorts = settings.Feed.ShowShorts
+		for _, channelID := range settings.Feed.Channels {
+			ui.selectedChannels[channelID] = true
+		}
+	}
+	ui.reload(ctx)
+
+	buf := make([]byte, 16)
+	for {
+		ui.draw()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, key := range splitKeys(string(buf[:n])) {
+			if !ui.handleKey(ctx, key) {
+				return nil
+			}
+		}
+	}
+}
+
+// splitKeys splits terminal input into key presses, as fast typing or
+// pasting can deliver several at once. Escape sequences like the arrow keys
+// are kept whole.
+func splitKeys(input string) []string {
+	var keys []string
+	for len(input) > 0 {
+		size := 1
+		if strings.HasPrefix(input, "\x1b[") || strings.HasPrefix(input, "\x1bO") {
+			// CSI and SS3 sequences end with a letter or "~".
+			size = 2
+			for size < len(input) {
+				c := input[size]
+				size++
+				if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '~' {
+					break
+				}
+			}
+		} else if _, runeSize := utf8.DecodeRuneInString(input); runeSize > 1 {
+			size = runeSize
+		}
+		keys = append(keys, input[:size])
+		input = input[size:]
+	}
+	return keys
+}
+
+// handleKey acts on a key press, returning false to quit.
+func (ui *feedUI) handleKey(ctx context.Context, key string) bool {
+	if key == "q" || key == "\x03" {
+		return false
+	}
+	if ui.pickingChannels {
+		ui.handleChannelKey(ctx, key)
+		return true
+	}
+
+	ui.status = ""
+	switch key {
+	case "k", "\x1b[A", "\x1bOA":
+		ui.selected--
+	case "j", "\x1b[B", "\x1bOB":
+		ui.selected++
+	case "\x1b[5~":
+		ui.selected -= ui.listHeight()
+	case "\x1b[6~", " ":
+		ui.selected += ui.listHeight()
+	case "g", "\x1b[H":
+		ui.selected = 0
+	case "G", "\x1b[F":
+		ui.selected = len(ui.videos) - 1
+	case "\r", "o":
+		if video, ok := ui.current(); ok {
+			ui.play(ctx, video)
+		}
+	case "w":
+		if video, ok := ui.current(); ok {
+			ui.setWatched(ctx, !video.Watched)
+		}
+	case "c":
+		ui.pickingChannels = true
+	case "s":
+		ui.showShorts = !ui.showShorts
+		ui.reload(ctx)
+	case "r":
+		ui.reload(ctx)
+	}
+
+	// Load the next page on reaching the end of what's loaded.
+	if ui.selected >= len(ui.videos)-1 && ui.nextCursor != "" {
+		ui.loadMore(ctx)
+	}
+	ui.selected = max(0, min(ui.selected, len(ui.videos)-1))
+	return true
+}
+
+func (ui *feedUI) handleChannelKey(ctx context.Context, key string) {
+	switch key {
+	case "k", "\x1b[A", "\x1bOA":
+		ui.channel--
+	case "j", "\x1b[B", "\x1bOB":
+		ui.channel++
+	case "\x1b[5~":
+		ui.channel -= ui.listHeight()
+	case "\x1b[6~":
+		ui.channel += ui.listHeight()
+	case " ", "x":
+		if ui.channel < len(ui.channels) {
+			channelID := ui.channels[ui.channel].ChannelID
+			if ui.selectedChannels[channelID] {
+				delete(ui.selectedChannels, channelID)
+			} else {
+				ui.selectedChannels[channelID] = true
+			}
+		}
+	case "a":
+		clear(ui.selectedChannels)
+	case "\r", "\x1b", "c":
+		ui.pickingChannels = false
+		ui.reload(ctx)
+	}
+	ui.channel = max(0, min(ui.channel, len(ui.channels)-1))
+}
+
+func (ui *feedUI) current() (api.Video, bool) {
+	if ui.selected < 0 || ui.selected >= len(ui.videos) {
+		return api.Video{}, false
+	}
+	return ui.videos[ui.selected], true
+}
+
+func (ui *feedUI) reload(ctx context.Context) {
+	ui.videos, ui.nextCursor, ui.selected, ui.offset = nil, "", 0, 0
+	ui.loadMore(ctx)
+}
+
+func (ui *feedUI) loadMore(ctx context.Context) {
+	ui.status = "Loading…"
+	ui.draw()
+
+	var channelIDs []string
+	for channelID := range ui.selectedChannels {
+		channelIDs = append(channelIDs, channelID)
+	}
+	page, err := ui.client.GetFeed(ctx, client.GetFeedParams{
+		Channel:    channelIDs,
+		ShowShorts: ui.showShorts,
+		Limit:      tuiPageSize,
+		Cursor:     ui.nextCursor,
+	})
+	if err != nil {
+		ui.status = err.Error()
+		return
+	}
+	ui.videos = append(ui.videos, page.Items...)
+	ui.nextCursor = page.NextCursor
+	ui.status = ""
+}
+
+// play opens the video in the player without waiting for it, and marks it
+// as watched.
+func (ui *feedUI) play(ctx context.Context, video api.Video) {
+	if len(ui.player) == 0 {
+		ui.status = "No player set"
+		return
+	}
+	cmd := exec.Command(ui.player[0], append(ui.player[1:], video.URL)...)
+	if err := cmd.Start(); err != nil {
+		ui.status = "Couldn't start the player: " + err.Error()
+		return
+	}
+	go cmd.Wait()
+
+	ui.status = "Playing " + video.Title
+	if !video.Watched {
+		ui.setWatched(ctx, true)
+	}
+}
+
+func (ui *feedUI) setWatched(ctx context.Context, watched bool) {
+	video := &ui.videos[ui.selected]
+	setWatched := ui.client.MarkUnwatched
+	if watched {
+		setWatched = ui.client.MarkWatched
+	}
+	if err := setWatched(ctx, video.VideoID); err != nil {
+		ui.status = err.Error()
+		return
+	}
+	video.Watched = watched
+}
+
+// listHeight is the number of rows available for the list, leaving room for
+// the header, help and status lines.
+func (ui *feedUI) listHeight() int {
+	_, height := terminalSize()
+	return max(1, height-3)
+}
+
+func (ui *feedUI) draw() {
+	width, _ := terminalSize()
+	rows := ui.listHeight()
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+
+	channelsLabel := "all channels"
+	if len(ui.selectedChannels) > 0 {
+		channelsLabel = fmt.Sprintf("%d channels", len(ui.selectedChannels))
+	}
+	shortsLabel := "Shorts hidden"
+	if ui.showShorts {
+		shortsLabel = "Shorts shown"
+	}
+	header := fmt.Sprintf(" yt_rss2 · %d videos · %s · %s", len(ui.videos), channelsLabel, shortsLabel)
+	if ui.nextCursor != "" {
+		header = fmt.Sprintf(" yt_rss2 · %d+ videos · %s · %s", len(ui.videos), channelsLabel, shortsLabel)
+	}
+	if ui.pickingChannels {
+		header = " Channels · space toggles, a selects all, enter goes back"
+	}
+	b.WriteString("\x1b[7m" + fitText(header, width) + "\x1b[0m\r\n")
+
+	if ui.pickingChannels {
+		ui.channelOffset = scrollOffset(ui.channel, ui.channelOffset, rows)
+		for i := ui.channelOffset; i < len(ui.channels) && i < ui.channelOffset+rows; i++ {
+			channel := ui.channels[i]
+			mark := "[ ]"
+			if ui.selectedChannels[channel.ChannelID] {
+				mark = "[x]"
+			}
+			line := fitText(" "+mark+" "+channelLabel(channel), width)
+			if i == ui.channel {
+				line = "\x1b[7m" + line + "\x1b[0m"
+			}
+			b.WriteString(line + "\r\n")
+		}
+	} else {
+		ui.offset = scrollOffset(ui.selected, ui.offset, rows)
+		now := time.Now()
+		for i := ui.offset; i < len(ui.videos) && i < ui.offset+rows; i++ {
+			line := fitText(videoLine(ui.videos[i], width, now), width)
+			switch {
+			case i == ui.selected:
+				line = "\x1b[7m" + line + "\x1b[0m"
+			case ui.videos[i].Watched:
+				line = "\x1b[2m" + line + "\x1b[0m"
+			}
+			b.WriteString(line + "\r\n")
+		}
+		if len(ui.videos) == 0 && ui.status == "" {
+			b.WriteString(" No videos.\r\n")
+		}
+	}
+
+	// The help and status lines go at the bottom.
+	_, height := terminalSize()
+	fmt.Fprintf(&b, "\x1b[%d;1H\x1b[2m%s\x1b[0m\r\n", height-1, fitText(" "+tuiHelp, width))
+	b.WriteString(fitText(" "+ui.status, width))
+	os.Stdout.WriteString(b.String())
+}
+
+// videoLine formats a video as a row of the list.
+func videoLine(video api.Video, width int, now time.Time) string {
+	mark := " "
+	switch {
+	case video.IsLive:
+		mark = "●"
+	case video.IsUpcoming:
+		mark = "◷"
+	case video.Watched:
+		mark = "✓"
+	case video.IsNew:
+		mark = "*"
+	}
+
+	channelWidth := min(20, width/4)
+	duration := formatSeconds(video.DurationSeconds)
+	left := fmt.Sprintf(" %s %4s  %s  ", mark, formatAge(video.PublishedAt, now), fitText(video.ChannelName, channelWidth))
+	titleWidth := max(0, width-len([]rune(left))-len(duration)-1)
+	return left + fitText(video.Title, titleWidth) + duration
+}
+
+func channelLabel(channel api.Channel) string {
+	if channel.Alias != "" {
+		return channel.Alias
+	}
+	return channel.Name
+}
+
+// scrollOffset returns the first row to show so that the selected row stays
+// on screen.
+func scrollOffset(selected, offset, rows int) int {
+	if selected < offset {
+		return selected
+	}
+	if selected >= offset+rows {
+		return selected - rows + 1
+	}
+	return offset
+}
+
+// fitText cuts s to width characters, or pads it with spaces.
+func fitText(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) > width {
+		if width <= 1 {
+			return string(runes[:width])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return s + strings.Repeat(" ", width-len(runes))
+}
+
+// formatAge gives a short age like "5m", "3h" or "2d".
+func formatAge(t, now time.Time) string {
+	age := now.Sub(t)
+	switch {
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", max(0, int(age.Minutes())))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	case age < 100*24*time.Hour:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	default:
+		return t.Format("1/06")
+	}
+}
+
+// formatSeconds formats a duration like "1:02:03" or "4:05", or gives ""
+// when it's unknown.
+func formatSeconds(seconds int64) string {
+	if seconds <= 0 {
+		return ""
+	}
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+func terminalSize() (int, int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}